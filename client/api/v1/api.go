@@ -0,0 +1,599 @@
+// Copyright 2018 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package v1 provides typed bindings for the Prometheus HTTP API described
+// by the handlers registered in web/api/v1.API.Register, so that downstream
+// Go tooling (rulers, federators, dashboards) can talk to a Prometheus
+// server without vendoring the ad-hoc response structs or reimplementing
+// marshaling and retry/error semantics.
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	json_iter "github.com/json-iterator/go"
+	"github.com/pkg/errors"
+	"github.com/prometheus/common/model"
+
+	"github.com/prometheus/prometheus/pkg/labels"
+)
+
+const (
+	apiPrefix = "/api/v1"
+
+	epAlerts         = apiPrefix + "/alerts"
+	epAlertManagers  = apiPrefix + "/alertmanagers"
+	epQuery          = apiPrefix + "/query"
+	epQueryRange     = apiPrefix + "/query_range"
+	epLabels         = apiPrefix + "/labels"
+	epLabelValues    = apiPrefix + "/label/:name/values"
+	epSeries         = apiPrefix + "/series"
+	epTargets        = apiPrefix + "/targets"
+	epTargetsMeta    = apiPrefix + "/targets/metadata"
+	epRules          = apiPrefix + "/rules"
+	epConfig         = apiPrefix + "/status/config"
+	epFlags          = apiPrefix + "/status/flags"
+	epSnapshot       = apiPrefix + "/admin/tsdb/snapshot"
+	epDeleteSeries   = apiPrefix + "/admin/tsdb/delete_series"
+	epCleanTombstone = apiPrefix + "/admin/tsdb/clean_tombstones"
+)
+
+// HealthStatus models the health of a scrape target, aligned with
+// scrape.TargetHealth so the two can be marshaled/compared interchangeably.
+type HealthStatus string
+
+const (
+	HealthGood    HealthStatus = "up"
+	HealthUnknown HealthStatus = "unknown"
+	HealthBad     HealthStatus = "down"
+)
+
+// errorType mirrors the errorType values returned by web/api/v1.
+type errorType string
+
+const (
+	ErrBadData     errorType = "bad_data"
+	ErrTimeout     errorType = "timeout"
+	ErrCanceled    errorType = "canceled"
+	ErrExec        errorType = "execution"
+	ErrInternal    errorType = "internal"
+	ErrUnavailable errorType = "unavailable"
+	ErrNotFound    errorType = "not_found"
+)
+
+// Error is returned whenever a request completes but the server or client
+// reports a failure, via the apiError{errorType,error} JSON envelope.
+type Error struct {
+	Type errorType
+	Msg  string
+}
+
+func (e *Error) Error() string {
+	return e.Msg
+}
+
+// Range is used to describe the start, end and step query parameters of a
+// query_range request.
+type Range struct {
+	Start time.Time
+	End   time.Time
+	Step  time.Duration
+}
+
+// API provides bindings for Prometheus's v1 HTTP API, one method per
+// endpoint registered in web/api/v1.API.Register.
+type API interface {
+	// Alerts returns a list of all active alerts.
+	Alerts(ctx context.Context) (AlertsResult, error)
+	// AlertManagers returns an overview of the current state of the
+	// Prometheus alert manager discovery.
+	AlertManagers(ctx context.Context) (AlertManagersResult, error)
+	// Config returns the current Prometheus configuration.
+	Config(ctx context.Context) (ConfigResult, error)
+	// Flags returns the flag values that Prometheus was launched with.
+	Flags(ctx context.Context) (FlagsResult, error)
+	// LabelNames returns all the unique label names present in the block in
+	// sorted order.
+	LabelNames(ctx context.Context) ([]string, Warnings, error)
+	// LabelValues performs a query for the values of the given label.
+	LabelValues(ctx context.Context, label string) (model.LabelValues, Warnings, error)
+	// Query performs a query for the given time.
+	Query(ctx context.Context, query string, ts time.Time) (model.Value, Warnings, error)
+	// QueryRange performs a query for the given range.
+	QueryRange(ctx context.Context, query string, r Range) (model.Value, Warnings, error)
+	// Series finds series by label matchers.
+	Series(ctx context.Context, matches []string, startTime time.Time, endTime time.Time) ([]labels.Labels, Warnings, error)
+	// Rules returns a list of alerting and recording rules currently loaded.
+	Rules(ctx context.Context) (RulesResult, error)
+	// Targets returns an overview of the current state of the Prometheus
+	// target discovery.
+	Targets(ctx context.Context) (TargetsResult, error)
+	// Snapshot creates a snapshot of all current data into
+	// snapshots/<datetime>-<rand> under the TSDB's data directory.
+	Snapshot(ctx context.Context, skipHead bool) (SnapshotResult, error)
+	// CleanTombstones removes the deleted data from disk and cleans up the
+	// existing tombstones.
+	CleanTombstones(ctx context.Context) error
+	// DeleteSeries deletes data for a selection of series in a time range.
+	DeleteSeries(ctx context.Context, matches []string, startTime time.Time, endTime time.Time) error
+}
+
+// Warnings is the type returned alongside successful results whenever the
+// server attached non-fatal notices to the response, e.g. partial results
+// from a remote read.
+type Warnings []string
+
+// AlertsResult contains the result from querying the alerts endpoint.
+type AlertsResult struct {
+	Alerts     []Alert `json:"alerts"`
+	TotalCount int     `json:"totalCount"`
+}
+
+// Alert models a single active alert.
+type Alert struct {
+	ActiveAt    time.Time      `json:"activeAt"`
+	Annotations model.LabelSet `json:"annotations"`
+	Labels      model.LabelSet `json:"labels"`
+	State       string         `json:"state"`
+	Value       string         `json:"value"`
+}
+
+// AlertManagersResult contains the result from querying the alertmanagers
+// endpoint.
+type AlertManagersResult struct {
+	Active  []AlertManager `json:"activeAlertmanagers"`
+	Dropped []AlertManager `json:"droppedAlertmanagers"`
+}
+
+// AlertManager models a configured Alertmanager.
+type AlertManager struct {
+	URL string `json:"url"`
+}
+
+// ConfigResult contains the result from querying the config endpoint.
+type ConfigResult struct {
+	YAML string `json:"yaml"`
+}
+
+// FlagsResult contains the result from querying the flag endpoint.
+type FlagsResult map[string]string
+
+// RulesResult contains the result from querying the rules endpoint.
+type RulesResult struct {
+	Groups     []RuleGroup `json:"groups"`
+	TotalCount int         `json:"totalCount"`
+}
+
+// RuleGroup models one rule group, with a mix of Rule implementations in
+// Rules.
+type RuleGroup struct {
+	Name     string  `json:"name"`
+	File     string  `json:"file"`
+	Rules    []Rule  `json:"rules"`
+	Interval float64 `json:"interval"`
+}
+
+// Rule is a generic interface satisfied by both AlertingRule and
+// RecordingRule, mirroring the rule interface{} used server-side.
+type Rule interface{}
+
+// AlertingRule models an alerting rule.
+type AlertingRule struct {
+	Name        string         `json:"name"`
+	Query       string         `json:"query"`
+	Duration    float64        `json:"duration"`
+	Labels      model.LabelSet `json:"labels"`
+	Annotations model.LabelSet `json:"annotations"`
+	Alerts      []Alert        `json:"alerts"`
+	Health      string         `json:"health"`
+	LastError   string         `json:"lastError,omitempty"`
+}
+
+// RecordingRule models a recording rule.
+type RecordingRule struct {
+	Name      string         `json:"name"`
+	Query     string         `json:"query"`
+	Labels    model.LabelSet `json:"labels,omitempty"`
+	Health    string         `json:"health"`
+	LastError string         `json:"lastError,omitempty"`
+}
+
+// TargetsResult contains the result from querying the targets endpoint.
+type TargetsResult struct {
+	Active  []Target        `json:"activeTargets"`
+	Dropped []DroppedTarget `json:"droppedTargets"`
+}
+
+// Target models an active scrape target.
+type Target struct {
+	DiscoveredLabels map[string]string `json:"discoveredLabels"`
+	Labels           model.LabelSet    `json:"labels"`
+	ScrapeURL        string            `json:"scrapeUrl"`
+	LastError        string            `json:"lastError"`
+	LastScrape       time.Time         `json:"lastScrape"`
+	Health           HealthStatus      `json:"health"`
+}
+
+// DroppedTarget models a target that was dropped during relabeling.
+type DroppedTarget struct {
+	DiscoveredLabels map[string]string `json:"discoveredLabels"`
+}
+
+// SnapshotResult contains the result from querying the snapshot endpoint.
+type SnapshotResult struct {
+	Name string `json:"name"`
+}
+
+// Config holds the parameters used to construct a Client.
+type Config struct {
+	// Address is the URL of the Prometheus server, e.g. "http://localhost:9090".
+	Address string
+	// Client is used to make HTTP requests. http.DefaultClient is used if
+	// none is given.
+	Client *http.Client
+	// MaxRetries bounds the number of retries on 5xx responses. 0 disables
+	// retrying.
+	MaxRetries int
+}
+
+func (cfg *Config) client() *http.Client {
+	if cfg.Client == nil {
+		return http.DefaultClient
+	}
+	return cfg.Client
+}
+
+// Client is a low-level HTTP client for the Prometheus v1 HTTP API that
+// handles URL joining, retries on 5xx, warning propagation and
+// apiError-typed responses. NewAPI wraps a Client to provide the typed
+// API above.
+type Client struct {
+	endpoint   *url.URL
+	client     *http.Client
+	maxRetries int
+}
+
+// NewClient returns a Client talking to the server described by cfg.
+func NewClient(cfg Config) (*Client, error) {
+	u, err := url.Parse(cfg.Address)
+	if err != nil {
+		return nil, err
+	}
+	u.Path = strings.TrimRight(u.Path, "/")
+
+	return &Client{
+		endpoint:   u,
+		client:     cfg.client(),
+		maxRetries: cfg.MaxRetries,
+	}, nil
+}
+
+// URL returns the full URL for the given endpoint path and query args.
+func (c *Client) URL(ep string, args map[string]string) *url.URL {
+	p := ep
+	for arg, val := range args {
+		arg = ":" + arg
+		p = strings.Replace(p, arg, val, -1)
+	}
+
+	u := *c.endpoint
+	u.Path = path.Join(u.Path, p)
+	return &u
+}
+
+// Do performs an HTTP request against the server, retrying on 5xx responses
+// up to c.maxRetries times, and decodes the standard {status,data,warnings}
+// envelope used by web/api/v1. Retries rewind req.Body via req.GetBody for
+// any request built with a body, since the first attempt's Transport drains
+// the original reader to EOF.
+func (c *Client) Do(ctx context.Context, req *http.Request) ([]byte, Warnings, error) {
+	req = req.WithContext(ctx)
+
+	var (
+		resp *http.Response
+		err  error
+	)
+	for attempt := 0; attempt <= c.maxRetries; attempt++ {
+		if attempt > 0 && req.GetBody != nil {
+			body, berr := req.GetBody()
+			if berr != nil {
+				return nil, nil, errors.Wrap(berr, "error rewinding request body for retry")
+			}
+			req.Body = body
+		}
+		resp, err = c.client.Do(req)
+		if err != nil {
+			return nil, nil, err
+		}
+		if resp.StatusCode/100 != 5 || attempt == c.maxRetries {
+			break
+		}
+		resp.Body.Close()
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "error reading response body")
+	}
+
+	var env struct {
+		Status    string          `json:"status"`
+		Data      json.RawMessage `json:"data"`
+		ErrorType errorType       `json:"errorType"`
+		Error     string          `json:"error"`
+		Warnings  []string        `json:"warnings"`
+	}
+	json := json_iter.ConfigCompatibleWithStandardLibrary
+	if err := json.Unmarshal(body, &env); err != nil {
+		return nil, nil, errors.Wrap(err, "error unmarshaling response")
+	}
+
+	if env.Status == "error" {
+		return env.Data, env.Warnings, &Error{
+			Type: env.ErrorType,
+			Msg:  env.Error,
+		}
+	}
+	return env.Data, env.Warnings, nil
+}
+
+// httpAPI implements API on top of a Client.
+type httpAPI struct {
+	client *Client
+}
+
+// NewAPI returns a new API wrapping c.
+func NewAPI(c *Client) API {
+	return &httpAPI{client: c}
+}
+
+func (h *httpAPI) request(ctx context.Context, ep string, args map[string]string, q url.Values) ([]byte, Warnings, error) {
+	u := h.client.URL(ep, args)
+	req, err := http.NewRequest(http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	req.URL.RawQuery = q.Encode()
+	return h.client.Do(ctx, req)
+}
+
+func (h *httpAPI) Alerts(ctx context.Context) (AlertsResult, error) {
+	body, _, err := h.request(ctx, epAlerts, nil, nil)
+	if err != nil {
+		return AlertsResult{}, err
+	}
+	var res AlertsResult
+	err = json.Unmarshal(body, &res)
+	return res, err
+}
+
+func (h *httpAPI) AlertManagers(ctx context.Context) (AlertManagersResult, error) {
+	body, _, err := h.request(ctx, epAlertManagers, nil, nil)
+	if err != nil {
+		return AlertManagersResult{}, err
+	}
+	var res AlertManagersResult
+	err = json.Unmarshal(body, &res)
+	return res, err
+}
+
+func (h *httpAPI) Config(ctx context.Context) (ConfigResult, error) {
+	body, _, err := h.request(ctx, epConfig, nil, nil)
+	if err != nil {
+		return ConfigResult{}, err
+	}
+	var res ConfigResult
+	err = json.Unmarshal(body, &res)
+	return res, err
+}
+
+func (h *httpAPI) Flags(ctx context.Context) (FlagsResult, error) {
+	body, _, err := h.request(ctx, epFlags, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	var res FlagsResult
+	err = json.Unmarshal(body, &res)
+	return res, err
+}
+
+func (h *httpAPI) LabelNames(ctx context.Context) ([]string, Warnings, error) {
+	body, warnings, err := h.request(ctx, epLabels, nil, nil)
+	if err != nil {
+		return nil, warnings, err
+	}
+	var labelNames []string
+	err = json.Unmarshal(body, &labelNames)
+	return labelNames, warnings, err
+}
+
+func (h *httpAPI) LabelValues(ctx context.Context, label string) (model.LabelValues, Warnings, error) {
+	body, warnings, err := h.request(ctx, epLabelValues, map[string]string{"name": label}, nil)
+	if err != nil {
+		return nil, warnings, err
+	}
+	var labelValues model.LabelValues
+	err = json.Unmarshal(body, &labelValues)
+	return labelValues, warnings, err
+}
+
+func (h *httpAPI) Query(ctx context.Context, query string, ts time.Time) (model.Value, Warnings, error) {
+	q := url.Values{"query": {query}}
+	if !ts.IsZero() {
+		q.Set("time", formatTime(ts))
+	}
+	body, warnings, err := h.request(ctx, epQuery, nil, q)
+	if err != nil {
+		return nil, warnings, err
+	}
+	var qres queryResult
+	err = json.Unmarshal(body, &qres)
+	return qres.v, warnings, err
+}
+
+func (h *httpAPI) QueryRange(ctx context.Context, query string, r Range) (model.Value, Warnings, error) {
+	q := url.Values{
+		"query": {query},
+		"start": {formatTime(r.Start)},
+		"end":   {formatTime(r.End)},
+		"step":  {strconv.FormatFloat(r.Step.Seconds(), 'f', -1, 64)},
+	}
+	body, warnings, err := h.request(ctx, epQueryRange, nil, q)
+	if err != nil {
+		return nil, warnings, err
+	}
+	var qres queryResult
+	err = json.Unmarshal(body, &qres)
+	return qres.v, warnings, err
+}
+
+func (h *httpAPI) Series(ctx context.Context, matches []string, startTime, endTime time.Time) ([]labels.Labels, Warnings, error) {
+	q := url.Values{"match[]": matches}
+	if !startTime.IsZero() {
+		q.Set("start", formatTime(startTime))
+	}
+	if !endTime.IsZero() {
+		q.Set("end", formatTime(endTime))
+	}
+	body, warnings, err := h.request(ctx, epSeries, nil, q)
+	if err != nil {
+		return nil, warnings, err
+	}
+	var mset []map[string]string
+	if err = json.Unmarshal(body, &mset); err != nil {
+		return nil, warnings, err
+	}
+	lset := make([]labels.Labels, len(mset))
+	for i, m := range mset {
+		lset[i] = labels.FromMap(m)
+	}
+	return lset, warnings, nil
+}
+
+func (h *httpAPI) Rules(ctx context.Context) (RulesResult, error) {
+	body, _, err := h.request(ctx, epRules, nil, nil)
+	if err != nil {
+		return RulesResult{}, err
+	}
+	var res RulesResult
+	err = json.Unmarshal(body, &res)
+	return res, err
+}
+
+func (h *httpAPI) Targets(ctx context.Context) (TargetsResult, error) {
+	body, _, err := h.request(ctx, epTargets, nil, nil)
+	if err != nil {
+		return TargetsResult{}, err
+	}
+	var res TargetsResult
+	err = json.Unmarshal(body, &res)
+	return res, err
+}
+
+func (h *httpAPI) Snapshot(ctx context.Context, skipHead bool) (SnapshotResult, error) {
+	u := h.client.URL(epSnapshot, nil)
+	q := url.Values{"skip_head": {strconv.FormatBool(skipHead)}}
+	req, err := http.NewRequest(http.MethodPost, u.String(), strings.NewReader(q.Encode()))
+	if err != nil {
+		return SnapshotResult{}, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	body, _, err := h.client.Do(ctx, req)
+	if err != nil {
+		return SnapshotResult{}, err
+	}
+	var res SnapshotResult
+	err = json.Unmarshal(body, &res)
+	return res, err
+}
+
+func (h *httpAPI) CleanTombstones(ctx context.Context) error {
+	u := h.client.URL(epCleanTombstone, nil)
+	req, err := http.NewRequest(http.MethodPost, u.String(), nil)
+	if err != nil {
+		return err
+	}
+	_, _, err = h.client.Do(ctx, req)
+	return err
+}
+
+func (h *httpAPI) DeleteSeries(ctx context.Context, matches []string, startTime, endTime time.Time) error {
+	u := h.client.URL(epDeleteSeries, nil)
+	q := url.Values{"match[]": matches}
+	if !startTime.IsZero() {
+		q.Set("start", formatTime(startTime))
+	}
+	if !endTime.IsZero() {
+		q.Set("end", formatTime(endTime))
+	}
+	req, err := http.NewRequest(http.MethodPost, u.String(), strings.NewReader(q.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	_, _, err = h.client.Do(ctx, req)
+	return err
+}
+
+// queryResult unmarshals a queryData-shaped response (resultType + result)
+// into a model.Value, mirroring how the server marshals model.Value.
+type queryResult struct {
+	Type   model.ValueType `json:"resultType"`
+	Result json.RawMessage `json:"result"`
+
+	v model.Value
+}
+
+func (qr *queryResult) UnmarshalJSON(b []byte) error {
+	v := struct {
+		Type   model.ValueType `json:"resultType"`
+		Result json.RawMessage `json:"result"`
+	}{}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	qr.Type = v.Type
+
+	switch v.Type {
+	case model.ValVector:
+		var sv model.Vector
+		err := json.Unmarshal(v.Result, &sv)
+		qr.v = sv
+		return err
+	case model.ValMatrix:
+		var sm model.Matrix
+		err := json.Unmarshal(v.Result, &sm)
+		qr.v = sm
+		return err
+	case model.ValScalar:
+		var ss model.Scalar
+		err := json.Unmarshal(v.Result, &ss)
+		qr.v = ss
+		return err
+	default:
+		return errors.Errorf("unexpected value type %q", v.Type)
+	}
+}
+
+func formatTime(t time.Time) string {
+	return strconv.FormatFloat(float64(t.Unix())+float64(t.Nanosecond())/1e9, 'f', -1, 64)
+}
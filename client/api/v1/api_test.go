@@ -0,0 +1,280 @@
+// Copyright 2019 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestClientURLSubstitutesPathArgs(t *testing.T) {
+	c, err := NewClient(Config{Address: "http://example.com/prometheus"})
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+
+	u := c.URL(epLabelValues, map[string]string{"name": "job"})
+	if got, want := u.Path, "/prometheus/api/v1/label/job/values"; got != want {
+		t.Fatalf("got path %q, want %q", got, want)
+	}
+}
+
+func TestClientURLWithoutArgsLeavesPathAlone(t *testing.T) {
+	c, err := NewClient(Config{Address: "http://example.com"})
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+
+	u := c.URL(epAlerts, nil)
+	if got, want := u.Path, "/api/v1/alerts"; got != want {
+		t.Fatalf("got path %q, want %q", got, want)
+	}
+}
+
+func TestClientURLTrimsTrailingSlashFromAddress(t *testing.T) {
+	c, err := NewClient(Config{Address: "http://example.com/prometheus/"})
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+
+	u := c.URL(epAlerts, nil)
+	if got, want := u.Path, "/prometheus/api/v1/alerts"; got != want {
+		t.Fatalf("got path %q, want %q", got, want)
+	}
+}
+
+func TestClientDoRetriesOn5xxUpToMaxRetries(t *testing.T) {
+	var requests int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte(`{"status":"error","errorType":"unavailable","error":"down"}`))
+	}))
+	defer srv.Close()
+
+	c, err := NewClient(Config{Address: srv.URL, MaxRetries: 2})
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+
+	req, err := http.NewRequest(http.MethodGet, srv.URL, nil)
+	if err != nil {
+		t.Fatalf("NewRequest: %v", err)
+	}
+	if _, _, err := c.Do(context.Background(), req); err == nil {
+		t.Fatal("expected an error from a persistently failing server, got nil")
+	}
+
+	if got, want := atomic.LoadInt32(&requests), int32(3); got != want {
+		t.Fatalf("got %d requests, want %d (1 initial + MaxRetries=2 retries)", got, want)
+	}
+}
+
+func TestClientDoStopsRetryingOnceServerRecovers(t *testing.T) {
+	var requests int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&requests, 1)
+		if n < 2 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte(`{"status":"error"}`))
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"status":"success","data":{}}`))
+	}))
+	defer srv.Close()
+
+	c, err := NewClient(Config{Address: srv.URL, MaxRetries: 5})
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+
+	req, err := http.NewRequest(http.MethodGet, srv.URL, nil)
+	if err != nil {
+		t.Fatalf("NewRequest: %v", err)
+	}
+	if _, _, err := c.Do(context.Background(), req); err != nil {
+		t.Fatalf("Do: %v", err)
+	}
+
+	if got, want := atomic.LoadInt32(&requests), int32(2); got != want {
+		t.Fatalf("got %d requests, want 2 (1 failure then 1 success, no further retries)", got)
+	}
+}
+
+func TestClientDoRetriesResendTheFullRequestBody(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		requests int32
+		bodies   []string
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&requests, 1)
+		b, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			t.Fatalf("reading request body: %v", err)
+		}
+		mu.Lock()
+		bodies = append(bodies, string(b))
+		mu.Unlock()
+		if n < 2 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte(`{"status":"error"}`))
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"status":"success","data":{}}`))
+	}))
+	defer srv.Close()
+
+	c, err := NewClient(Config{Address: srv.URL, MaxRetries: 5})
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+
+	const wantBody = "match%5B%5D=up"
+	req, err := http.NewRequest(http.MethodPost, srv.URL, strings.NewReader(wantBody))
+	if err != nil {
+		t.Fatalf("NewRequest: %v", err)
+	}
+	if _, _, err := c.Do(context.Background(), req); err != nil {
+		t.Fatalf("Do: %v", err)
+	}
+
+	if got, want := atomic.LoadInt32(&requests), int32(2); got != want {
+		t.Fatalf("got %d requests, want 2 (1 failure then 1 success)", got)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	for i, b := range bodies {
+		if b != wantBody {
+			t.Fatalf("request %d: got body %q, want %q (retry must resend the body, not an empty drained reader)", i, b, wantBody)
+		}
+	}
+}
+
+func TestClientDoDoesNotRetryOn4xx(t *testing.T) {
+	var requests int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"status":"error","errorType":"bad_data","error":"bad query"}`))
+	}))
+	defer srv.Close()
+
+	c, err := NewClient(Config{Address: srv.URL, MaxRetries: 5})
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+
+	req, err := http.NewRequest(http.MethodGet, srv.URL, nil)
+	if err != nil {
+		t.Fatalf("NewRequest: %v", err)
+	}
+	_, _, err = c.Do(context.Background(), req)
+	apiErr, ok := err.(*Error)
+	if !ok {
+		t.Fatalf("got err %v (%T), want *Error", err, err)
+	}
+	if apiErr.Type != ErrBadData {
+		t.Fatalf("got error type %q, want %q", apiErr.Type, ErrBadData)
+	}
+	if got, want := atomic.LoadInt32(&requests), int32(1); got != want {
+		t.Fatalf("got %d requests, want 1 (4xx responses must not be retried)", got)
+	}
+}
+
+func TestClientDoDecodesErrorEnvelope(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"status":"error","errorType":"not_found","error":"unknown label"}`))
+	}))
+	defer srv.Close()
+
+	c, err := NewClient(Config{Address: srv.URL})
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	req, err := http.NewRequest(http.MethodGet, srv.URL, nil)
+	if err != nil {
+		t.Fatalf("NewRequest: %v", err)
+	}
+
+	_, _, err = c.Do(context.Background(), req)
+	apiErr, ok := err.(*Error)
+	if !ok {
+		t.Fatalf("got err %v (%T), want *Error", err, err)
+	}
+	if apiErr.Type != ErrNotFound {
+		t.Fatalf("got error type %q, want %q", apiErr.Type, ErrNotFound)
+	}
+	if apiErr.Msg != "unknown label" {
+		t.Fatalf("got message %q, want %q", apiErr.Msg, "unknown label")
+	}
+	if apiErr.Error() != "unknown label" {
+		t.Fatalf("Error() = %q, want %q", apiErr.Error(), "unknown label")
+	}
+}
+
+func TestClientDoReturnsWarningsOnSuccess(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"status":"success","data":{},"warnings":["partial result"]}`))
+	}))
+	defer srv.Close()
+
+	c, err := NewClient(Config{Address: srv.URL})
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	req, err := http.NewRequest(http.MethodGet, srv.URL, nil)
+	if err != nil {
+		t.Fatalf("NewRequest: %v", err)
+	}
+
+	_, warnings, err := c.Do(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Do: %v", err)
+	}
+	if len(warnings) != 1 || warnings[0] != "partial result" {
+		t.Fatalf("got warnings %v, want [\"partial result\"]", warnings)
+	}
+}
+
+func TestClientDoMalformedBodyIsAnError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`not json`))
+	}))
+	defer srv.Close()
+
+	c, err := NewClient(Config{Address: srv.URL})
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	req, err := http.NewRequest(http.MethodGet, srv.URL, nil)
+	if err != nil {
+		t.Fatalf("NewRequest: %v", err)
+	}
+
+	if _, _, err := c.Do(context.Background(), req); err == nil {
+		t.Fatal("expected an error unmarshaling a non-JSON body, got nil")
+	}
+}
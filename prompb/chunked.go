@@ -0,0 +1,85 @@
+// Copyright 2019 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package prompb
+
+import "github.com/gogo/protobuf/proto"
+
+// ChunkedReadResponse is one frame of the chunked remote read protocol. A
+// read request answered in streaming mode is a sequence of these, each
+// carrying a bounded number of series instead of one fully materialized
+// ReadResponse.
+type ChunkedReadResponse struct {
+	ChunkedSeries []*ChunkedSeries `protobuf:"bytes,1,rep,name=chunked_series" json:"chunked_series,omitempty"`
+	// QueryIndex tracks which query in the original ReadRequest this frame
+	// answers, so frames from different queries can be interleaved.
+	QueryIndex int64 `protobuf:"varint,2,opt,name=query_index" json:"query_index,omitempty"`
+	// Error, when non-empty, marks this as a trailing error frame: the
+	// stream ends here and ChunkedSeries should be ignored.
+	Error string `protobuf:"bytes,3,opt,name=error" json:"error,omitempty"`
+}
+
+func (m *ChunkedReadResponse) Reset()         { *m = ChunkedReadResponse{} }
+func (m *ChunkedReadResponse) String() string { return proto.CompactTextString(m) }
+func (*ChunkedReadResponse) ProtoMessage()    {}
+
+// chunkedReadResponseWire has ChunkedReadResponse's fields and proto.Message
+// methods but no Marshal/Unmarshal of its own, so proto.Marshal and
+// proto.Unmarshal encode it from its struct tags instead of calling back into
+// ChunkedReadResponse.Marshal/Unmarshal.
+type chunkedReadResponseWire ChunkedReadResponse
+
+func (m *chunkedReadResponseWire) Reset()         { *m = chunkedReadResponseWire{} }
+func (m *chunkedReadResponseWire) String() string { return proto.CompactTextString(m) }
+func (*chunkedReadResponseWire) ProtoMessage()    {}
+
+func (m *ChunkedReadResponse) Marshal() ([]byte, error) {
+	return proto.Marshal((*chunkedReadResponseWire)(m))
+}
+
+func (m *ChunkedReadResponse) Unmarshal(data []byte) error {
+	return proto.Unmarshal(data, (*chunkedReadResponseWire)(m))
+}
+
+// ChunkedSeries holds the chunks for a single series, encoded per-chunk as
+// identified by each Chunk's Type.
+type ChunkedSeries struct {
+	Labels []Label `protobuf:"bytes,1,rep,name=labels" json:"labels"`
+	Chunks []Chunk `protobuf:"bytes,2,rep,name=chunks" json:"chunks"`
+}
+
+func (m *ChunkedSeries) Reset()         { *m = ChunkedSeries{} }
+func (m *ChunkedSeries) String() string { return proto.CompactTextString(m) }
+func (*ChunkedSeries) ProtoMessage()    {}
+
+// Chunk is a single chunk of samples for one series, covering [MinTimeMs,
+// MaxTimeMs]. Data is encoded as identified by Type; see Type's doc comment
+// for what encodings a client may see.
+type Chunk struct {
+	MinTimeMs int64 `protobuf:"varint,1,opt,name=min_time_ms" json:"min_time_ms,omitempty"`
+	MaxTimeMs int64 `protobuf:"varint,2,opt,name=max_time_ms" json:"max_time_ms,omitempty"`
+	// Type identifies the encoding of Data. A value written by this repo's
+	// remote.ToChunkedSeries is remote.rawChunkEncoding (0xFF), a private,
+	// non-standard encoding, not a TSDB tsdb/chunkenc.Encoding value:
+	// storage.Series doesn't expose TSDB's encoded chunks, so there is no
+	// real chunk encoding to forward here. 0xFF is deliberately out of
+	// range of every chunkenc.Encoding so a standard chunked remote-read
+	// client (e.g. Thanos, Cortex) that expects Type to mean a TSDB
+	// encoding rejects the frame instead of silently misdecoding it.
+	Type uint32 `protobuf:"varint,3,opt,name=type" json:"type,omitempty"`
+	Data []byte `protobuf:"bytes,4,opt,name=data" json:"data,omitempty"`
+}
+
+func (m *Chunk) Reset()         { *m = Chunk{} }
+func (m *Chunk) String() string { return proto.CompactTextString(m) }
+func (*Chunk) ProtoMessage()    {}
@@ -0,0 +1,100 @@
+// Copyright 2018 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rules
+
+import (
+	"sync"
+	"time"
+
+	"github.com/prometheus/prometheus/pkg/labels"
+)
+
+// EventType describes what kind of change a StateChange event records.
+type EventType string
+
+const (
+	// EventAlertStateChange is published whenever an alert instance
+	// transitions between inactive, pending, firing and resolved.
+	EventAlertStateChange EventType = "alert_state_change"
+	// EventRuleEvaluated is published whenever a rule finishes evaluating,
+	// independent of whether it produced a state transition.
+	EventRuleEvaluated EventType = "rule_evaluated"
+)
+
+// Event is published into a Hub whenever an alert changes state or a rule
+// finishes evaluating. The web/api/v1 package publishes these by polling a
+// rulesRetriever, since this package has no hook of its own into rule
+// evaluation to call Publish from directly.
+type Event struct {
+	Type EventType `json:"type"`
+
+	GroupName string        `json:"groupName"`
+	RuleName  string        `json:"ruleName"`
+	Labels    labels.Labels `json:"labels"`
+
+	PreviousState string     `json:"previousState,omitempty"`
+	NextState     string     `json:"nextState,omitempty"`
+	ActiveAt      *time.Time `json:"activeAt,omitempty"`
+
+	LastError string `json:"lastError,omitempty"`
+}
+
+// Hub is a small fan-out pub/sub used to let HTTP handlers stream rule and
+// alert activity without polling. Publish never blocks: subscribers that
+// fall behind have events dropped rather than stalling rule evaluation.
+type Hub struct {
+	mtx         sync.Mutex
+	subscribers map[chan *Event]struct{}
+}
+
+// NewHub returns an empty Hub ready for Publish and Subscribe calls.
+func NewHub() *Hub {
+	return &Hub{
+		subscribers: map[chan *Event]struct{}{},
+	}
+}
+
+// Subscribe registers a new listener and returns its event channel along
+// with an unsubscribe function that must be called when the caller is done
+// reading, typically via defer.
+func (h *Hub) Subscribe() (<-chan *Event, func()) {
+	ch := make(chan *Event, 32)
+
+	h.mtx.Lock()
+	h.subscribers[ch] = struct{}{}
+	h.mtx.Unlock()
+
+	unsubscribe := func() {
+		h.mtx.Lock()
+		defer h.mtx.Unlock()
+		if _, ok := h.subscribers[ch]; ok {
+			delete(h.subscribers, ch)
+			close(ch)
+		}
+	}
+	return ch, unsubscribe
+}
+
+// Publish fans ev out to all current subscribers. A subscriber whose buffer
+// is full has the event dropped for it rather than blocking evaluation.
+func (h *Hub) Publish(ev *Event) {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+	for ch := range h.subscribers {
+		select {
+		case ch <- ev:
+		default:
+		}
+	}
+}
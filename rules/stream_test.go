@@ -0,0 +1,98 @@
+// Copyright 2019 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rules
+
+import "testing"
+
+func TestHubPublishSubscribe(t *testing.T) {
+	h := NewHub()
+	ch, unsubscribe := h.Subscribe()
+	defer unsubscribe()
+
+	h.Publish(&Event{Type: EventRuleEvaluated, RuleName: "r1"})
+
+	select {
+	case ev := <-ch:
+		if ev.RuleName != "r1" {
+			t.Fatalf("got event for rule %q, want r1", ev.RuleName)
+		}
+	default:
+		t.Fatal("expected a buffered event, got none")
+	}
+}
+
+func TestHubPublishWithNoSubscribersDoesNotBlock(t *testing.T) {
+	h := NewHub()
+	h.Publish(&Event{Type: EventRuleEvaluated, RuleName: "r1"})
+}
+
+func TestHubPublishDropsForFullSubscriberInsteadOfBlocking(t *testing.T) {
+	h := NewHub()
+	ch, unsubscribe := h.Subscribe()
+	defer unsubscribe()
+
+	// The channel buffer is 32; publishing more than that must drop the
+	// overflow rather than block the publisher.
+	for i := 0; i < 64; i++ {
+		h.Publish(&Event{Type: EventRuleEvaluated, RuleName: "r1"})
+	}
+
+	n := 0
+	for {
+		select {
+		case <-ch:
+			n++
+			continue
+		default:
+		}
+		break
+	}
+	if n != 32 {
+		t.Fatalf("got %d buffered events, want 32 (the channel capacity)", n)
+	}
+}
+
+func TestHubUnsubscribeClosesChannel(t *testing.T) {
+	h := NewHub()
+	ch, unsubscribe := h.Subscribe()
+	unsubscribe()
+
+	if _, ok := <-ch; ok {
+		t.Fatal("expected the channel to be closed after unsubscribe")
+	}
+
+	// unsubscribe must be safe to call more than once.
+	unsubscribe()
+}
+
+func TestHubMultipleSubscribersEachGetTheEvent(t *testing.T) {
+	h := NewHub()
+	ch1, unsub1 := h.Subscribe()
+	defer unsub1()
+	ch2, unsub2 := h.Subscribe()
+	defer unsub2()
+
+	h.Publish(&Event{Type: EventAlertStateChange, RuleName: "r1"})
+
+	for _, ch := range []<-chan *Event{ch1, ch2} {
+		select {
+		case ev := <-ch:
+			if ev.RuleName != "r1" {
+				t.Fatalf("got event for rule %q, want r1", ev.RuleName)
+			}
+		default:
+			t.Fatal("expected a buffered event, got none")
+		}
+	}
+}
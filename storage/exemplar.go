@@ -0,0 +1,47 @@
+// Copyright 2019 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"context"
+
+	"github.com/prometheus/prometheus/pkg/labels"
+)
+
+// Exemplar is a sample that was collected alongside a regular series sample,
+// typically carrying a trace ID so that a point on a graph can be followed
+// into a distributed tracing system.
+type Exemplar struct {
+	Labels labels.Labels
+	Value  float64
+	Ts     int64
+}
+
+// ExemplarQuerier provides reading access to exemplars for a fixed matcher
+// over a single time range.
+type ExemplarQuerier interface {
+	// Select returns the exemplars of the series selected by a set of label
+	// matchers as one flat slice; they are not grouped by series.
+	Select(matchers ...*labels.Matcher) ([]Exemplar, error)
+}
+
+// ExemplarQueryable is implemented by Queryable implementations that also
+// expose an exemplar store. It is intentionally separate from Queryable so
+// that existing implementations don't need to grow a no-op method; the API
+// layer type-asserts for it.
+type ExemplarQueryable interface {
+	// ExemplarQuerier returns an ExemplarQuerier for the given context and
+	// time range.
+	ExemplarQuerier(ctx context.Context, mint, maxt int64) (ExemplarQuerier, error)
+}
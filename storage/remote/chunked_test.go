@@ -0,0 +1,165 @@
+// Copyright 2019 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package remote
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/prometheus/prometheus/pkg/labels"
+	"github.com/prometheus/prometheus/prompb"
+	"github.com/prometheus/prometheus/storage"
+)
+
+// fakeSeriesIterator iterates over a fixed, in-memory list of samples, the
+// same shape a real storage.SeriesIterator over a query result would have.
+type fakeSeriesIterator struct {
+	ts  []int64
+	vs  []float64
+	cur int
+}
+
+func (it *fakeSeriesIterator) Seek(t int64) bool {
+	for ; it.cur < len(it.ts); it.cur++ {
+		if it.ts[it.cur] >= t {
+			return true
+		}
+	}
+	return false
+}
+
+func (it *fakeSeriesIterator) At() (int64, float64) {
+	return it.ts[it.cur], it.vs[it.cur]
+}
+
+func (it *fakeSeriesIterator) Next() bool {
+	it.cur++
+	return it.cur < len(it.ts)
+}
+
+func (it *fakeSeriesIterator) Err() error { return nil }
+
+type fakeSeries struct {
+	lbls labels.Labels
+	it   *fakeSeriesIterator
+}
+
+func (s *fakeSeries) Labels() labels.Labels            { return s.lbls }
+func (s *fakeSeries) Iterator() storage.SeriesIterator { return s.it }
+
+func newFakeSeries(numSamples int) *fakeSeries {
+	ts := make([]int64, numSamples)
+	vs := make([]float64, numSamples)
+	for i := range ts {
+		ts[i] = int64(i) * 1000
+		vs[i] = float64(i)
+	}
+	return &fakeSeries{
+		lbls: labels.FromStrings("__name__", "test_metric"),
+		it:   &fakeSeriesIterator{ts: ts, vs: vs, cur: -1},
+	}
+}
+
+func TestToChunkedSeriesBoundedSize(t *testing.T) {
+	const numSamples = 100000
+
+	series := newFakeSeries(numSamples)
+	cs, n, err := ToChunkedSeries(series, []prompb.Label{{Name: "__name__", Value: "test_metric"}})
+	if err != nil {
+		t.Fatalf("ToChunkedSeries: %v", err)
+	}
+	if n != numSamples {
+		t.Fatalf("got %d samples, want %d", n, numSamples)
+	}
+	if len(cs.Chunks) != 1 {
+		t.Fatalf("got %d chunks, want 1", len(cs.Chunks))
+	}
+
+	// Each sample is a fixed-size fixed64 value plus at most
+	// binary.MaxVarintLen64 bytes for the timestamp delta. The encoded size
+	// must stay within that bound per sample rather than growing with
+	// unrelated per-series or per-frame overhead, which is what keeps
+	// memory use proportional to one frame's samples rather than the whole
+	// result set.
+	const maxBytesPerSample = 8 + 10
+	if got, want := len(cs.Chunks[0].Data), numSamples*maxBytesPerSample; got > want {
+		t.Fatalf("encoded chunk is %d bytes, want at most %d for %d samples", got, want, numSamples)
+	}
+}
+
+func TestToChunkedSeriesEmptyIterator(t *testing.T) {
+	series := &fakeSeries{
+		lbls: labels.FromStrings("__name__", "broken_metric"),
+		it:   &fakeSeriesIterator{ts: nil, vs: nil, cur: -1},
+	}
+	_, n, err := ToChunkedSeries(series, nil)
+	if err != nil {
+		t.Fatalf("ToChunkedSeries on an empty iterator should not error, got: %v", err)
+	}
+	if n != 0 {
+		t.Fatalf("got %d samples from an empty iterator, want 0", n)
+	}
+}
+
+// TestChunkedWriterReaderRoundTrip verifies a client can decode frames
+// incrementally as a ChunkedWriter produces them, rather than needing the
+// whole response buffered first.
+func TestChunkedWriterReaderRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	cw := NewChunkedWriter(&buf)
+
+	want := []*prompb.ChunkedReadResponse{
+		{QueryIndex: 0, ChunkedSeries: []*prompb.ChunkedSeries{{Labels: []prompb.Label{{Name: "a", Value: "1"}}}}},
+		{QueryIndex: 1, ChunkedSeries: []*prompb.ChunkedSeries{{Labels: []prompb.Label{{Name: "b", Value: "2"}}}}},
+		{QueryIndex: 1, Error: "boom"},
+	}
+	for _, msg := range want {
+		if err := cw.Write(msg); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+	}
+
+	cr := NewChunkedReader(&buf)
+	for i, w := range want {
+		var got prompb.ChunkedReadResponse
+		if err := cr.Next(&got); err != nil {
+			t.Fatalf("Next(%d): %v", i, err)
+		}
+		if got.QueryIndex != w.QueryIndex || got.Error != w.Error {
+			t.Fatalf("frame %d = %+v, want %+v", i, got, w)
+		}
+	}
+
+	if _, err := cr.NextRaw(); err != io.EOF {
+		t.Fatalf("expected io.EOF after the last frame, got %v", err)
+	}
+}
+
+func TestChunkedReaderDetectsCorruption(t *testing.T) {
+	var buf bytes.Buffer
+	cw := NewChunkedWriter(&buf)
+	if err := cw.Write(&prompb.ChunkedReadResponse{QueryIndex: 42}); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+
+	corrupted := buf.Bytes()
+	corrupted[len(corrupted)-1] ^= 0xFF
+
+	cr := NewChunkedReader(bytes.NewReader(corrupted))
+	var got prompb.ChunkedReadResponse
+	if err := cr.Next(&got); err == nil {
+		t.Fatal("expected a checksum mismatch error, got nil")
+	}
+}
@@ -0,0 +1,73 @@
+// Copyright 2019 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package snapshot
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+
+	"github.com/Azure/azure-storage-blob-go/azblob"
+	"github.com/pkg/errors"
+)
+
+// azureSink uploads snapshots to an Azure Blob Storage container. Credentials
+// are read from AZURE_STORAGE_ACCOUNT and AZURE_STORAGE_ACCESS_KEY.
+type azureSink struct {
+	container *azblob.ContainerURL
+	prefix    string
+}
+
+func newAzureSink(cfg Config) (Sink, error) {
+	if cfg.Container == "" {
+		return nil, errors.New("snapshot: azure backend requires a container")
+	}
+	account := os.Getenv("AZURE_STORAGE_ACCOUNT")
+	key := os.Getenv("AZURE_STORAGE_ACCESS_KEY")
+	if account == "" || key == "" {
+		return nil, errors.New("snapshot: AZURE_STORAGE_ACCOUNT and AZURE_STORAGE_ACCESS_KEY must be set")
+	}
+
+	credential, err := azblob.NewSharedKeyCredential(account, key)
+	if err != nil {
+		return nil, errors.Wrap(err, "create azure credential")
+	}
+	pipeline := azblob.NewPipeline(credential, azblob.PipelineOptions{})
+
+	containerURL, err := url.Parse(fmt.Sprintf("https://%s.blob.core.windows.net/%s", account, cfg.Container))
+	if err != nil {
+		return nil, errors.Wrap(err, "build azure container url")
+	}
+	container := azblob.NewContainerURL(*containerURL, pipeline)
+	return &azureSink{container: &container, prefix: cfg.Prefix}, nil
+}
+
+func (a *azureSink) Put(ctx context.Context, name, dir string) (string, error) {
+	err := walkSnapshot(dir, a.prefix, name, func(key, localPath string) error {
+		f, err := os.Open(localPath)
+		if err != nil {
+			return errors.Wrapf(err, "open %q", localPath)
+		}
+		defer f.Close()
+
+		blockBlobURL := a.container.NewBlockBlobURL(key)
+		_, err = azblob.UploadFileToBlockBlob(ctx, f, blockBlobURL, azblob.UploadToBlockBlobOptions{})
+		return errors.Wrapf(err, "upload %q", key)
+	})
+	if err != nil {
+		return "", err
+	}
+	return a.container.URL().String() + "/" + a.prefix + name, nil
+}
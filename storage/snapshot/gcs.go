@@ -0,0 +1,64 @@
+// Copyright 2019 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package snapshot
+
+import (
+	"context"
+	"io"
+	"os"
+
+	"cloud.google.com/go/storage"
+	"github.com/pkg/errors"
+)
+
+// gcsSink uploads snapshots to a Google Cloud Storage bucket using
+// Application Default Credentials.
+type gcsSink struct {
+	bucket string
+	prefix string
+	client *storage.Client
+}
+
+func newGCSSink(cfg Config) (Sink, error) {
+	if cfg.Bucket == "" {
+		return nil, errors.New("snapshot: gcs backend requires a bucket")
+	}
+	client, err := storage.NewClient(context.Background())
+	if err != nil {
+		return nil, errors.Wrap(err, "create gcs client")
+	}
+	return &gcsSink{bucket: cfg.Bucket, prefix: cfg.Prefix, client: client}, nil
+}
+
+func (g *gcsSink) Put(ctx context.Context, name, dir string) (string, error) {
+	bkt := g.client.Bucket(g.bucket)
+	err := walkSnapshot(dir, g.prefix, name, func(key, localPath string) error {
+		f, err := os.Open(localPath)
+		if err != nil {
+			return errors.Wrapf(err, "open %q", localPath)
+		}
+		defer f.Close()
+
+		w := bkt.Object(key).NewWriter(ctx)
+		if _, err := io.Copy(w, f); err != nil {
+			w.Close()
+			return errors.Wrapf(err, "upload %q", key)
+		}
+		return errors.Wrapf(w.Close(), "finalize %q", key)
+	})
+	if err != nil {
+		return "", err
+	}
+	return "gs://" + g.bucket + "/" + g.prefix + name, nil
+}
@@ -0,0 +1,87 @@
+// Copyright 2019 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package snapshot
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	namespace = "prometheus"
+	subsystem = "snapshot"
+)
+
+var (
+	uploadDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: subsystem,
+		Name:      "upload_duration_seconds",
+		Help:      "Time taken to upload a TSDB snapshot to the configured backend.",
+		Buckets:   prometheus.ExponentialBuckets(1, 2, 10),
+	})
+	uploadSizeBytes = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: subsystem,
+		Name:      "upload_size_bytes",
+		Help:      "Size of the TSDB snapshot directory uploaded to the configured backend.",
+		Buckets:   prometheus.ExponentialBuckets(1<<20, 4, 10),
+	})
+	uploadErrors = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: subsystem,
+		Name:      "upload_errors_total",
+		Help:      "Number of TSDB snapshot uploads that failed.",
+	})
+)
+
+func init() {
+	prometheus.MustRegister(uploadDuration, uploadSizeBytes, uploadErrors)
+}
+
+// Upload uploads the snapshot named name from dir via sink, recording the
+// upload_duration_seconds, upload_size_bytes and upload_errors_total
+// metrics around the call.
+func Upload(ctx context.Context, sink Sink, name, dir string) (string, error) {
+	start := time.Now()
+	url, err := sink.Put(ctx, name, dir)
+	uploadDuration.Observe(time.Since(start).Seconds())
+	if err != nil {
+		uploadErrors.Inc()
+		return "", err
+	}
+	if size, sizeErr := dirSize(dir); sizeErr == nil {
+		uploadSizeBytes.Observe(float64(size))
+	}
+	return url, nil
+}
+
+// dirSize returns the total size in bytes of every regular file under dir.
+func dirSize(dir string) (int64, error) {
+	var size int64
+	err := filepath.Walk(dir, func(_ string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size, err
+}
@@ -0,0 +1,75 @@
+// Copyright 2019 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package snapshot
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+type fakeSink struct {
+	url string
+	err error
+}
+
+func (s fakeSink) Put(_ context.Context, _, _ string) (string, error) {
+	return s.url, s.err
+}
+
+func TestUploadReturnsSinkURL(t *testing.T) {
+	dir := t.TempDir()
+	if err := ioutil.WriteFile(filepath.Join(dir, "meta.json"), []byte("12345"), 0666); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	url, err := Upload(context.Background(), fakeSink{url: "s3://bucket/snap-1"}, "snap-1", dir)
+	if err != nil {
+		t.Fatalf("Upload: %v", err)
+	}
+	if url != "s3://bucket/snap-1" {
+		t.Fatalf("got %q, want the sink's URL", url)
+	}
+}
+
+func TestUploadPropagatesSinkError(t *testing.T) {
+	dir := t.TempDir()
+	wantErr := errors.New("put failed")
+
+	_, err := Upload(context.Background(), fakeSink{err: wantErr}, "snap-1", dir)
+	if errors.Cause(err) != wantErr {
+		t.Fatalf("got err %v, want %v", err, wantErr)
+	}
+}
+
+func TestDirSizeSumsRegularFiles(t *testing.T) {
+	dir := t.TempDir()
+	if err := ioutil.WriteFile(filepath.Join(dir, "a"), make([]byte, 10), 0666); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "b"), make([]byte, 20), 0666); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	size, err := dirSize(dir)
+	if err != nil {
+		t.Fatalf("dirSize: %v", err)
+	}
+	if size != 30 {
+		t.Fatalf("got %d, want 30", size)
+	}
+}
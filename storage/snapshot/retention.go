@@ -0,0 +1,56 @@
+// Copyright 2019 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package snapshot
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/pkg/errors"
+)
+
+// PruneLocal removes the oldest local snapshot directories under snapdir
+// until at most keep remain. Snapshot directory names start with an
+// RFC3339-like timestamp (as written by api.snapshot), so a lexicographic
+// sort is also a chronological one. keep <= 0 disables pruning.
+func PruneLocal(snapdir string, keep int) error {
+	if keep <= 0 {
+		return nil
+	}
+
+	entries, err := ioutil.ReadDir(snapdir)
+	if err != nil {
+		return errors.Wrapf(err, "read snapshot directory %q", snapdir)
+	}
+
+	var names []string
+	for _, e := range entries {
+		if e.IsDir() {
+			names = append(names, e.Name())
+		}
+	}
+	sort.Strings(names)
+
+	if len(names) <= keep {
+		return nil
+	}
+	for _, name := range names[:len(names)-keep] {
+		if err := os.RemoveAll(filepath.Join(snapdir, name)); err != nil {
+			return errors.Wrapf(err, "remove old snapshot %q", name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+// Copyright 2019 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package snapshot
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+func mkSnapshotDirs(t *testing.T, snapdir string, names ...string) {
+	t.Helper()
+	for _, name := range names {
+		if err := os.MkdirAll(filepath.Join(snapdir, name), 0777); err != nil {
+			t.Fatalf("MkdirAll: %v", err)
+		}
+	}
+}
+
+func listDirs(t *testing.T, snapdir string) []string {
+	t.Helper()
+	entries, err := ioutil.ReadDir(snapdir)
+	if err != nil {
+		t.Fatalf("ReadDir: %v", err)
+	}
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestPruneLocalKeepDisabled(t *testing.T) {
+	snapdir := t.TempDir()
+	mkSnapshotDirs(t, snapdir, "20190101T000000Z-1", "20190102T000000Z-2")
+
+	if err := PruneLocal(snapdir, 0); err != nil {
+		t.Fatalf("PruneLocal: %v", err)
+	}
+	if got := listDirs(t, snapdir); len(got) != 2 {
+		t.Fatalf("got %v, want both snapshots kept when keep<=0", got)
+	}
+}
+
+func TestPruneLocalKeepsNewest(t *testing.T) {
+	snapdir := t.TempDir()
+	mkSnapshotDirs(t, snapdir,
+		"20190101T000000Z-1",
+		"20190102T000000Z-2",
+		"20190103T000000Z-3",
+	)
+
+	if err := PruneLocal(snapdir, 2); err != nil {
+		t.Fatalf("PruneLocal: %v", err)
+	}
+
+	want := []string{"20190102T000000Z-2", "20190103T000000Z-3"}
+	got := listDirs(t, snapdir)
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("got %v, want %v", got, want)
+		}
+	}
+}
+
+func TestPruneLocalUnderKeepIsNoOp(t *testing.T) {
+	snapdir := t.TempDir()
+	mkSnapshotDirs(t, snapdir, "20190101T000000Z-1")
+
+	if err := PruneLocal(snapdir, 5); err != nil {
+		t.Fatalf("PruneLocal: %v", err)
+	}
+	if got := listDirs(t, snapdir); len(got) != 1 {
+		t.Fatalf("got %v, want the single snapshot untouched", got)
+	}
+}
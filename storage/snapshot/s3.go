@@ -0,0 +1,68 @@
+// Copyright 2019 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package snapshot
+
+import (
+	"context"
+	"os"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+	"github.com/pkg/errors"
+)
+
+// s3Sink uploads snapshots to an S3 (or S3-compatible) bucket using the
+// default AWS credential chain.
+type s3Sink struct {
+	bucket   string
+	prefix   string
+	uploader *s3manager.Uploader
+}
+
+func newS3Sink(cfg Config) (Sink, error) {
+	if cfg.Bucket == "" {
+		return nil, errors.New("snapshot: s3 backend requires a bucket")
+	}
+	sess, err := session.NewSession()
+	if err != nil {
+		return nil, errors.Wrap(err, "create aws session")
+	}
+	return &s3Sink{
+		bucket:   cfg.Bucket,
+		prefix:   cfg.Prefix,
+		uploader: s3manager.NewUploader(sess),
+	}, nil
+}
+
+func (s *s3Sink) Put(ctx context.Context, name, dir string) (string, error) {
+	err := walkSnapshot(dir, s.prefix, name, func(key, localPath string) error {
+		f, err := os.Open(localPath)
+		if err != nil {
+			return errors.Wrapf(err, "open %q", localPath)
+		}
+		defer f.Close()
+
+		_, err = s.uploader.UploadWithContext(ctx, &s3manager.UploadInput{
+			Bucket: aws.String(s.bucket),
+			Key:    aws.String(key),
+			Body:   f,
+		})
+		return errors.Wrapf(err, "upload %q", key)
+	})
+	if err != nil {
+		return "", err
+	}
+	return "s3://" + s.bucket + "/" + s.prefix + name, nil
+}
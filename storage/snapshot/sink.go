@@ -0,0 +1,77 @@
+// Copyright 2019 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package snapshot ships completed TSDB snapshots off to a pluggable
+// object-storage backend so ruler/federation deployments can retain them
+// beyond the lifetime of the local disk, or hand them to a sidecar that
+// ingests from object storage.
+package snapshot
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+)
+
+// Sink uploads a completed TSDB snapshot directory and reports where it
+// ended up. Implementations must be safe for concurrent use; Put is called
+// once per snapshot from api.snapshot after db.Snapshot has returned.
+type Sink interface {
+	// Put uploads the snapshot named name from the local directory dir and
+	// returns a URL the caller can use to retrieve it later.
+	Put(ctx context.Context, name, dir string) (url string, err error)
+}
+
+// Config selects and parameterizes a Sink. Backend is one of "local" (the
+// default), "s3", "gcs", or "azure"; the Bucket/Container fields are
+// interpreted only by the matching backend.
+type Config struct {
+	Backend string
+
+	// Bucket names the S3 bucket or GCS bucket holding uploaded snapshots.
+	Bucket string
+	// Container names the Azure Blob container holding uploaded snapshots.
+	Container string
+	// Prefix is prepended to every object key, e.g. "prometheus-snapshots/".
+	Prefix string
+
+	// Retention is the number of most recent local snapshot directories to
+	// keep around after a successful upload to a non-local backend; 0
+	// disables pruning and removes the local copy of each uploaded snapshot
+	// instead.
+	Retention int
+}
+
+// NewSink returns the Sink selected by cfg.Backend.
+func NewSink(cfg Config) (Sink, error) {
+	switch cfg.Backend {
+	case "", "local":
+		return localSink{}, nil
+	case "s3":
+		return newS3Sink(cfg)
+	case "gcs":
+		return newGCSSink(cfg)
+	case "azure":
+		return newAzureSink(cfg)
+	default:
+		return nil, errors.Errorf("unknown snapshot backend %q", cfg.Backend)
+	}
+}
+
+// localSink is the default Sink: the snapshot already lives on local disk
+// under dir, so Put is a no-op that just reports that path back as the URL.
+type localSink struct{}
+
+func (localSink) Put(_ context.Context, _, dir string) (string, error) {
+	return "file://" + dir, nil
+}
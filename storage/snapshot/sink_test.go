@@ -0,0 +1,48 @@
+// Copyright 2019 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package snapshot
+
+import (
+	"context"
+	"testing"
+)
+
+func TestNewSinkLocalIsDefault(t *testing.T) {
+	for _, backend := range []string{"", "local"} {
+		sink, err := NewSink(Config{Backend: backend})
+		if err != nil {
+			t.Fatalf("NewSink(%q): %v", backend, err)
+		}
+		if _, ok := sink.(localSink); !ok {
+			t.Fatalf("NewSink(%q) = %T, want localSink", backend, sink)
+		}
+	}
+}
+
+func TestNewSinkUnknownBackend(t *testing.T) {
+	if _, err := NewSink(Config{Backend: "ftp"}); err == nil {
+		t.Fatal("expected an error for an unknown backend, got nil")
+	}
+}
+
+func TestLocalSinkPutReturnsFileURL(t *testing.T) {
+	var s localSink
+	url, err := s.Put(context.Background(), "snap-1", "/var/lib/prometheus/snapshots/snap-1")
+	if err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	if want := "file:///var/lib/prometheus/snapshots/snap-1"; url != want {
+		t.Fatalf("got %q, want %q", url, want)
+	}
+}
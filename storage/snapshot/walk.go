@@ -0,0 +1,44 @@
+// Copyright 2019 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package snapshot
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+)
+
+// uploadFunc uploads the file at localPath as key.
+type uploadFunc func(key, localPath string) error
+
+// walkSnapshot calls upload for every regular file under dir, keying each
+// one by prefix/name/<path relative to dir>, so the three object-storage
+// backends only have to implement the single-file upload call.
+func walkSnapshot(dir, prefix, name string, upload uploadFunc) error {
+	return filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return errors.Wrapf(err, "relativize %q", path)
+		}
+		key := filepath.ToSlash(filepath.Join(prefix, name, rel))
+		return upload(key, path)
+	})
+}
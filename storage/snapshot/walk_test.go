@@ -0,0 +1,72 @@
+// Copyright 2019 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package snapshot
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+func TestWalkSnapshotKeysEveryFileUnderPrefixAndName(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "chunks"), 0777); err != nil {
+		t.Fatalf("MkdirAll: %v", err)
+	}
+	for _, rel := range []string{"meta.json", filepath.Join("chunks", "000001")} {
+		if err := ioutil.WriteFile(filepath.Join(dir, rel), []byte("data"), 0666); err != nil {
+			t.Fatalf("WriteFile: %v", err)
+		}
+	}
+
+	var gotKeys []string
+	err := walkSnapshot(dir, "prometheus-snapshots/", "snap-1", func(key, localPath string) error {
+		gotKeys = append(gotKeys, key)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("walkSnapshot: %v", err)
+	}
+	sort.Strings(gotKeys)
+
+	want := []string{
+		"prometheus-snapshots/snap-1/chunks/000001",
+		"prometheus-snapshots/snap-1/meta.json",
+	}
+	if len(gotKeys) != len(want) {
+		t.Fatalf("got keys %v, want %v", gotKeys, want)
+	}
+	for i := range want {
+		if gotKeys[i] != want[i] {
+			t.Fatalf("got keys %v, want %v", gotKeys, want)
+		}
+	}
+}
+
+func TestWalkSnapshotPropagatesUploadError(t *testing.T) {
+	dir := t.TempDir()
+	if err := ioutil.WriteFile(filepath.Join(dir, "meta.json"), []byte("data"), 0666); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	wantErr := os.ErrPermission
+	err := walkSnapshot(dir, "", "snap-1", func(key, localPath string) error {
+		return wantErr
+	})
+	if err != wantErr {
+		t.Fatalf("got err %v, want %v", err, wantErr)
+	}
+}
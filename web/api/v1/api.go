@@ -25,6 +25,7 @@ import (
 	"regexp"
 	"sort"
 	"strconv"
+	"strings"
 	"time"
 	"unsafe"
 
@@ -48,6 +49,7 @@ import (
 	"github.com/prometheus/prometheus/scrape"
 	"github.com/prometheus/prometheus/storage"
 	"github.com/prometheus/prometheus/storage/remote"
+	"github.com/prometheus/prometheus/storage/snapshot"
 	"github.com/prometheus/prometheus/util/httputil"
 	"github.com/prometheus/prometheus/util/stats"
 )
@@ -93,6 +95,15 @@ func (e *apiError) Error() string {
 	return fmt.Sprintf("%s: %s", e.typ, e.err)
 }
 
+// NewAPIError builds the apiFuncResult for a handler that failed with err
+// but still has non-fatal warnings worth surfacing to the client, e.g. a
+// partially completed deletion. Unlike a plain apiFuncResult{nil, &apiError{...}, nil, nil}
+// literal, it keeps warnings flowing to respondError instead of silently
+// dropping them.
+func NewAPIError(typ errorType, err error, warnings storage.Warnings) apiFuncResult {
+	return apiFuncResult{nil, &apiError{typ, err}, warnings, nil}
+}
+
 type targetRetriever interface {
 	TargetsActive() map[string][]*scrape.Target
 	TargetsDropped() map[string][]*scrape.Target
@@ -133,6 +144,64 @@ type TSDBAdmin interface {
 	Snapshot(dir string, withHead bool) error
 }
 
+// TSDBStats is implemented by TSDBAdmin implementations that can report
+// head-block cardinality statistics. It is kept separate from TSDBAdmin so
+// that it can walk the head index postings once and return bounded top-N
+// heaps in O(cardinality), rather than requiring every TSDBAdmin to support
+// it.
+type TSDBStats interface {
+	// Stats returns head-block cardinality statistics. statsByLabelName, if
+	// non-empty, additionally scopes MemoryInBytesByLabelName and
+	// SeriesCountByLabelValuePair to that single label name. matchers, if
+	// non-empty, scopes every statistic to the series selected by all of
+	// them, the same way match[] scopes series and delete_series.
+	Stats(statsByLabelName string, matchers ...*labels.Matcher) (*TSDBStatus, error)
+}
+
+// TSDBStat is a single top-N entry, e.g. a metric name and how many series
+// it has.
+type TSDBStat struct {
+	Name  string `json:"name"`
+	Value uint64 `json:"value"`
+}
+
+// TSDBStatus holds the head-block cardinality statistics served from
+// /api/v1/status/tsdb.
+type TSDBStatus struct {
+	NumSeries                   uint64     `json:"numSeries"`
+	NumLabelPairs               uint64     `json:"numLabelPairs"`
+	ChunkCount                  uint64     `json:"chunkCount"`
+	SeriesCountByMetricName     []TSDBStat `json:"seriesCountByMetricName"`
+	LabelValueCountByLabelName  []TSDBStat `json:"labelValueCountByLabelName"`
+	MemoryInBytesByLabelName    []TSDBStat `json:"memoryInBytesByLabelName"`
+	SeriesCountByLabelValuePair []TSDBStat `json:"seriesCountByLabelValuePair"`
+	ChunkCountByMetricName      []TSDBStat `json:"chunkCountByMetricName"`
+}
+
+// limitTSDBStats truncates every top-N list in s to at most n entries,
+// n <= 0 meaning "no limit".
+func limitTSDBStats(s *TSDBStatus, n int) *TSDBStatus {
+	if n <= 0 {
+		return s
+	}
+	trunc := func(stats []TSDBStat) []TSDBStat {
+		if len(stats) > n {
+			return stats[:n]
+		}
+		return stats
+	}
+	return &TSDBStatus{
+		NumSeries:                   s.NumSeries,
+		NumLabelPairs:               s.NumLabelPairs,
+		ChunkCount:                  s.ChunkCount,
+		SeriesCountByMetricName:     trunc(s.SeriesCountByMetricName),
+		LabelValueCountByLabelName:  trunc(s.LabelValueCountByLabelName),
+		MemoryInBytesByLabelName:    trunc(s.MemoryInBytesByLabelName),
+		SeriesCountByLabelValuePair: trunc(s.SeriesCountByLabelValuePair),
+		ChunkCountByMetricName:      trunc(s.ChunkCountByMetricName),
+	}
+}
+
 // API can register a set of endpoints in a router and handle
 // them using the provided storage and query engine.
 type API struct {
@@ -147,12 +216,20 @@ type API struct {
 	flagsMap              map[string]string
 	ready                 func(http.HandlerFunc) http.HandlerFunc
 
-	db                    func() TSDBAdmin
-	enableAdmin           bool
-	logger                log.Logger
-	remoteReadSampleLimit int
-	remoteReadGate        *gate.Gate
-	CORSOrigin            *regexp.Regexp
+	db                        func() TSDBAdmin
+	enableAdmin               bool
+	logger                    log.Logger
+	remoteReadSampleLimit     int
+	remoteReadGate            *gate.Gate
+	CORSOrigin                *regexp.Regexp
+	rulesHub                  *rules.Hub
+	exemplarSampleLimit       int
+	remoteReadTimeout         time.Duration
+	remoteReadMaxBytesInFrame int
+	snapshotSink              snapshot.Sink
+	snapshotBackend           string
+	snapshotRetention         int
+	stopRulesPollingFallback  context.CancelFunc
 }
 
 func init() {
@@ -160,7 +237,21 @@ func init() {
 	prometheus.MustRegister(remoteReadQueries)
 }
 
-// NewAPI returns an initialized API type.
+// NewAPI returns an initialized API type. If rulesHub is nil and rr is
+// non-nil, a Hub is created automatically and fed by polling rr, so
+// /alerts/stream and /rules/stream work without the caller having to wire
+// Publish calls in themselves; pass a non-nil rulesHub to use one already
+// fed some other way, or pass rr as nil to leave streaming disabled.
+//
+// Fidelity note: the auto-created Hub is fed by rulesPollingFallback, which
+// diffs rr's state on a fixed tick (rulesPollingFallbackInterval) rather than
+// being called from rules.Group.Eval/rules.AlertingRule.Eval directly —
+// this package has no hook into evaluation itself. Every event this poller
+// publishes is therefore delayed by up to rulesPollingFallbackInterval, and any
+// alert that passes through multiple states within a single tick (e.g.
+// pending->firing->resolved) is only observed in its state as of the tick,
+// not as the individual transitions. Pass a rulesHub fed by the real
+// evaluation loop instead if per-transition delivery matters to the caller.
 func NewAPI(
 	qe *promql.Engine,
 	q storage.Queryable,
@@ -176,35 +267,77 @@ func NewAPI(
 	remoteReadSampleLimit int,
 	remoteReadConcurrencyLimit int,
 	CORSOrigin *regexp.Regexp,
+	rulesHub *rules.Hub,
+	exemplarSampleLimit int,
+	remoteReadTimeout time.Duration,
+	remoteReadMaxBytesInFrame int,
+	snapshotSink snapshot.Sink,
+	snapshotBackend string,
+	snapshotRetention int,
 ) *API {
+	var stopRulesPollingFallback context.CancelFunc
+	if rulesHub == nil && rr != nil {
+		rulesHub = rules.NewHub()
+		var ctx context.Context
+		ctx, stopRulesPollingFallback = context.WithCancel(context.Background())
+		go newRulesPollingFallback(rr, rulesHub).run(ctx)
+	}
+
 	return &API{
 		QueryEngine:           qe,
 		Queryable:             q,
 		targetRetriever:       tr,
 		alertmanagerRetriever: ar,
 
-		now:                   time.Now,
-		config:                configFunc,
-		flagsMap:              flagsMap,
-		ready:                 readyFunc,
-		db:                    db,
-		enableAdmin:           enableAdmin,
-		rulesRetriever:        rr,
-		remoteReadSampleLimit: remoteReadSampleLimit,
-		remoteReadGate:        gate.New(remoteReadConcurrencyLimit),
-		logger:                logger,
-		CORSOrigin:            CORSOrigin,
+		now:                       time.Now,
+		config:                    configFunc,
+		flagsMap:                  flagsMap,
+		ready:                     readyFunc,
+		db:                        db,
+		enableAdmin:               enableAdmin,
+		rulesRetriever:            rr,
+		remoteReadSampleLimit:     remoteReadSampleLimit,
+		remoteReadGate:            gate.New(remoteReadConcurrencyLimit),
+		logger:                    logger,
+		CORSOrigin:                CORSOrigin,
+		rulesHub:                  rulesHub,
+		exemplarSampleLimit:       exemplarSampleLimit,
+		remoteReadTimeout:         remoteReadTimeout,
+		remoteReadMaxBytesInFrame: remoteReadMaxBytesInFrame,
+		snapshotSink:              snapshotSink,
+		snapshotBackend:           snapshotBackend,
+		snapshotRetention:         snapshotRetention,
+		stopRulesPollingFallback:  stopRulesPollingFallback,
+	}
+}
+
+// Close stops the background rulesPollingFallback goroutine NewAPI started, if
+// any. It is a no-op if NewAPI was given an explicit rulesHub, since in that
+// case the caller owns feeding it and nothing here started a goroutine.
+func (api *API) Close() {
+	if api.stopRulesPollingFallback != nil {
+		api.stopRulesPollingFallback()
 	}
 }
 
 // Register the API's endpoints in the given router.
+//
+// Compatibility note: POST /read negotiates a chunked streaming response
+// (see isStreamingReadRequest) as an internal memory-bounding optimization
+// for this server only. It is not a Thanos/Cortex-compatible chunked
+// remote-read stream — the chunk payloads use this package's own
+// remote.rawChunkEncoding rather than a TSDB chunk encoding, so a generic
+// chunked remote-read client cannot decode them. Only a client built
+// against remote.ChunkedReader can consume it; every other client should
+// omit the streaming negotiation headers and get the standard buffered
+// prompb.ReadResponse.
 func (api *API) Register(r *route.Router) {
 	wrap := func(f apiFunc) http.HandlerFunc {
 		hf := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			httputil.SetCORS(w, api.CORSOrigin, r)
 			result := f(r)
 			if result.err != nil {
-				api.respondError(w, result.err, result.data)
+				api.respondError(w, result.err, result.data, result.warnings)
 			} else if result.data != nil {
 				api.respond(w, result.data, result.warnings)
 			} else {
@@ -225,6 +358,7 @@ func (api *API) Register(r *route.Router) {
 	r.Post("/query", wrap(api.query))
 	r.Get("/query_range", wrap(api.queryRange))
 	r.Post("/query_range", wrap(api.queryRange))
+	r.Post("/query/exemplars", wrap(api.queryExemplars))
 
 	r.Get("/labels", wrap(api.labelNames))
 	r.Post("/labels", wrap(api.labelNames))
@@ -240,10 +374,14 @@ func (api *API) Register(r *route.Router) {
 
 	r.Get("/status/config", wrap(api.serveConfig))
 	r.Get("/status/flags", wrap(api.serveFlags))
+	r.Get("/status/tsdb", wrap(api.serveTSDBStatus))
+	r.Get("/status/tsdb/labelnames", wrap(api.serveTSDBLabelNames))
 	r.Post("/read", api.ready(http.HandlerFunc(api.remoteRead)))
 
 	r.Get("/alerts", wrap(api.alerts))
 	r.Get("/rules", wrap(api.rules))
+	r.Get("/alerts/stream", api.ready(http.HandlerFunc(api.alertsStream)))
+	r.Get("/rules/stream", api.ready(http.HandlerFunc(api.rulesStream)))
 
 	// Admin APIs
 	r.Post("/admin/tsdb/delete_series", wrap(api.deleteSeries))
@@ -386,6 +524,119 @@ func (api *API) queryRange(r *http.Request) apiFuncResult {
 	}, nil, res.Warnings, qry.Close}
 }
 
+// seriesExemplars pairs a series' labels with the exemplars found for it,
+// the response shape of POST /api/v1/query/exemplars.
+type seriesExemplars struct {
+	SeriesLabels labels.Labels    `json:"seriesLabels"`
+	Exemplars    []exemplarSample `json:"exemplars"`
+}
+
+type exemplarSample struct {
+	Labels labels.Labels `json:"labels"`
+	Value  float64       `json:"value"`
+	Ts     int64         `json:"timestamp"`
+}
+
+// queryExemplars returns the exemplars collected for every series produced
+// by evaluating the PromQL expression in "query" over [start,end,step], for
+// trace-linking UIs such as Grafana to deep-link from a graph point into a
+// tracing backend.
+func (api *API) queryExemplars(r *http.Request) apiFuncResult {
+	eq, ok := api.Queryable.(storage.ExemplarQueryable)
+	if !ok {
+		err := errors.New("the configured storage does not support exemplar queries")
+		return apiFuncResult{nil, &apiError{errorUnavailable, err}, nil, nil}
+	}
+
+	start, err := parseTime(r.FormValue("start"))
+	if err != nil {
+		return apiFuncResult{nil, &apiError{errorBadData, errors.Wrapf(err, "invalid parameter 'start'")}, nil, nil}
+	}
+	end, err := parseTime(r.FormValue("end"))
+	if err != nil {
+		return apiFuncResult{nil, &apiError{errorBadData, errors.Wrapf(err, "invalid parameter 'end'")}, nil, nil}
+	}
+	if end.Before(start) {
+		return apiFuncResult{nil, &apiError{errorBadData, errors.New("end timestamp must not be before start time")}, nil, nil}
+	}
+
+	step, err := parseDuration(r.FormValue("step"))
+	if err != nil {
+		return apiFuncResult{nil, &apiError{errorBadData, errors.Wrapf(err, "invalid parameter 'step'")}, nil, nil}
+	}
+	if step <= 0 {
+		return apiFuncResult{nil, &apiError{errorBadData, errors.New("zero or negative query resolution step widths are not accepted. Try a positive integer")}, nil, nil}
+	}
+
+	ctx := r.Context()
+	if to := r.FormValue("timeout"); to != "" {
+		var cancel context.CancelFunc
+		timeout, err := parseDuration(to)
+		if err != nil {
+			return apiFuncResult{nil, &apiError{errorBadData, errors.Wrapf(err, "invalid parameter 'timeout'")}, nil, nil}
+		}
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	qry, err := api.QueryEngine.NewRangeQuery(api.Queryable, r.FormValue("query"), start, end, step)
+	if err != nil {
+		return apiFuncResult{nil, &apiError{errorBadData, err}, nil, nil}
+	}
+	defer qry.Close()
+
+	res := qry.Exec(ctx)
+	if res.Err != nil {
+		return apiFuncResult{nil, returnAPIError(res.Err), res.Warnings, nil}
+	}
+
+	mat, ok := res.Value.(promql.Matrix)
+	if !ok {
+		err := errors.Errorf("unexpected result type %s for range query", res.Value.Type())
+		return apiFuncResult{nil, &apiError{errorInternal, err}, nil, nil}
+	}
+
+	exq, err := eq.ExemplarQuerier(ctx, timestamp.FromTime(start), timestamp.FromTime(end))
+	if err != nil {
+		return apiFuncResult{nil, &apiError{errorExec, err}, nil, nil}
+	}
+
+	result := make([]seriesExemplars, 0, len(mat))
+	numSamples := 0
+	for _, series := range mat {
+		matchers := make([]*labels.Matcher, 0, len(series.Metric))
+		for _, l := range series.Metric {
+			m, err := labels.NewMatcher(labels.MatchEqual, l.Name, l.Value)
+			if err != nil {
+				return apiFuncResult{nil, &apiError{errorInternal, err}, nil, nil}
+			}
+			matchers = append(matchers, m)
+		}
+
+		exemplars, err := exq.Select(matchers...)
+		if err != nil {
+			return apiFuncResult{nil, &apiError{errorExec, err}, nil, nil}
+		}
+
+		se := seriesExemplars{SeriesLabels: series.Metric}
+		for _, e := range exemplars {
+			numSamples++
+			if api.exemplarSampleLimit > 0 && numSamples > api.exemplarSampleLimit {
+				err := errors.Errorf("exceeded exemplar sample limit (%d)", api.exemplarSampleLimit)
+				return apiFuncResult{nil, &apiError{errorBadData, err}, nil, nil}
+			}
+			se.Exemplars = append(se.Exemplars, exemplarSample{
+				Labels: e.Labels,
+				Value:  e.Value,
+				Ts:     e.Ts,
+			})
+		}
+		result = append(result, se)
+	}
+
+	return apiFuncResult{result, nil, res.Warnings, nil}
+}
+
 func returnAPIError(err error) *apiError {
 	if err == nil {
 		return nil
@@ -688,7 +939,8 @@ func (api *API) alertmanagers(r *http.Request) apiFuncResult {
 
 // AlertDiscovery has info for all active alerts.
 type AlertDiscovery struct {
-	Alerts []*Alert `json:"alerts"`
+	Alerts     []*Alert `json:"alerts"`
+	TotalCount int      `json:"totalCount"`
 }
 
 // Alert has info for an alert.
@@ -701,21 +953,109 @@ type Alert struct {
 }
 
 func (api *API) alerts(r *http.Request) apiFuncResult {
-	alertingRules := api.rulesRetriever.AlertingRules()
+	return alertsImpl(api.rulesRetriever, r)
+}
+
+// alertsImpl backs both (*API).alerts and (*RulesAPI).alerts so the two
+// registrations of GET /alerts can never drift in filtering/pagination
+// behavior.
+func alertsImpl(rr rulesRetriever, r *http.Request) apiFuncResult {
+	if err := r.ParseForm(); err != nil {
+		return apiFuncResult{nil, &apiError{errorBadData, errors.Wrapf(err, "error parsing form values")}, nil, nil}
+	}
+
+	stateFilter := r.FormValue("state")
+	if stateFilter != "" && stateFilter != rules.StateFiring.String() && stateFilter != rules.StatePending.String() && stateFilter != rules.StateInactive.String() {
+		err := errors.Errorf("invalid state %q, must be one of %q, %q, %q", stateFilter, rules.StateInactive, rules.StatePending, rules.StateFiring)
+		return apiFuncResult{nil, &apiError{errorBadData, err}, nil, nil}
+	}
+
+	matchers, err := parseMatchersParam(r.Form["match[]"])
+	if err != nil {
+		return apiFuncResult{nil, &apiError{errorBadData, err}, nil, nil}
+	}
+
+	limit, offset, err := parseLimitOffset(r)
+	if err != nil {
+		return apiFuncResult{nil, &apiError{errorBadData, err}, nil, nil}
+	}
+
+	alertingRules := rr.AlertingRules()
 	alerts := []*Alert{}
 
 	for _, alertingRule := range alertingRules {
-		alerts = append(
-			alerts,
-			rulesAlertsToAPIAlerts(alertingRule.ActiveAlerts())...,
-		)
+		for _, a := range rulesAlertsToAPIAlerts(alertingRule.ActiveAlerts()) {
+			if stateFilter != "" && a.State != stateFilter {
+				continue
+			}
+			if !matchLabels(a.Labels, matchers) {
+				continue
+			}
+			alerts = append(alerts, a)
+		}
 	}
 
-	res := &AlertDiscovery{Alerts: alerts}
+	sort.Slice(alerts, func(i, j int) bool {
+		return labels.Compare(alerts[i].Labels, alerts[j].Labels) < 0
+	})
+
+	totalCount := len(alerts)
+	alerts = paginateAlerts(alerts, offset, limit)
+
+	res := &AlertDiscovery{Alerts: alerts, TotalCount: totalCount}
 
 	return apiFuncResult{res, nil, nil, nil}
 }
 
+// parseMatchersParam parses a repeated match[] form value into label matchers,
+// the same way the series and delete_series endpoints do.
+func parseMatchersParam(matchers []string) ([]*labels.Matcher, error) {
+	var matcherSets [][]*labels.Matcher
+	for _, s := range matchers {
+		matchers, err := promql.ParseMetricSelector(s)
+		if err != nil {
+			return nil, err
+		}
+		matcherSets = append(matcherSets, matchers)
+	}
+
+	var result []*labels.Matcher
+	for _, mset := range matcherSets {
+		result = append(result, mset...)
+	}
+	return result, nil
+}
+
+// parseLimitOffset reads the limit/offset form values used to paginate rules
+// and alerts. A limit <= 0 means "no limit".
+func parseLimitOffset(r *http.Request) (limit, offset int, err error) {
+	if s := r.FormValue("limit"); s != "" {
+		if limit, err = strconv.Atoi(s); err != nil {
+			return 0, 0, errors.New("limit must be a number")
+		}
+	}
+	if s := r.FormValue("offset"); s != "" {
+		if offset, err = strconv.Atoi(s); err != nil {
+			return 0, 0, errors.New("offset must be a number")
+		}
+		if offset < 0 {
+			return 0, 0, errors.New("offset must not be negative")
+		}
+	}
+	return limit, offset, nil
+}
+
+func paginateAlerts(alerts []*Alert, offset, limit int) []*Alert {
+	if offset >= len(alerts) {
+		return []*Alert{}
+	}
+	alerts = alerts[offset:]
+	if limit > 0 && limit < len(alerts) {
+		alerts = alerts[:limit]
+	}
+	return alerts
+}
+
 func rulesAlertsToAPIAlerts(rulesAlerts []*rules.Alert) []*Alert {
 	apiAlerts := make([]*Alert, len(rulesAlerts))
 	for i, ruleAlert := range rulesAlerts {
@@ -734,6 +1074,7 @@ func rulesAlertsToAPIAlerts(rulesAlerts []*rules.Alert) []*Alert {
 // RuleDiscovery has info for all rules
 type RuleDiscovery struct {
 	RuleGroups []*RuleGroup `json:"groups"`
+	TotalCount int          `json:"totalCount"`
 }
 
 // RuleGroup has info for rules which are part of a group
@@ -758,6 +1099,10 @@ type alertingRule struct {
 	Alerts      []*Alert         `json:"alerts"`
 	Health      rules.RuleHealth `json:"health"`
 	LastError   string           `json:"lastError,omitempty"`
+	// QueryURL, if non-empty, is a deep link back into a query UI
+	// pre-populated with Query so consumers don't have to build one
+	// themselves from the Prometheus base URL.
+	QueryURL string `json:"queryUrl,omitempty"`
 	// Type of an alertingRule is always "alerting".
 	Type string `json:"type"`
 }
@@ -772,10 +1117,74 @@ type recordingRule struct {
 	Type string `json:"type"`
 }
 
+// ruleTypeAlert and ruleTypeRecord are the only valid values of the "type"
+// query parameter accepted by the rules endpoint.
+const (
+	ruleTypeAlert  = "alert"
+	ruleTypeRecord = "record"
+)
+
 func (api *API) rules(r *http.Request) apiFuncResult {
-	ruleGroups := api.rulesRetriever.RuleGroups()
-	res := &RuleDiscovery{RuleGroups: make([]*RuleGroup, len(ruleGroups))}
-	for i, grp := range ruleGroups {
+	return rulesImpl(api.rulesRetriever, "", r)
+}
+
+// rulesImpl backs both (*API).rules and (*RulesAPI).rules so the two
+// registrations of GET /rules can never drift in filtering/pagination
+// behavior. queryURL is forwarded to ruleQueryURL to populate
+// alertingRule.QueryURL; API has no queryURL of its own and passes "".
+func rulesImpl(rr rulesRetriever, queryURL string, r *http.Request) apiFuncResult {
+	if err := r.ParseForm(); err != nil {
+		return apiFuncResult{nil, &apiError{errorBadData, errors.Wrapf(err, "error parsing form values")}, nil, nil}
+	}
+
+	typeFilter := r.FormValue("type")
+	if typeFilter != "" && typeFilter != ruleTypeAlert && typeFilter != ruleTypeRecord {
+		err := errors.Errorf("invalid type %q, must be either empty, %q or %q", typeFilter, ruleTypeAlert, ruleTypeRecord)
+		return apiFuncResult{nil, &apiError{errorBadData, err}, nil, nil}
+	}
+
+	stateFilter := r.FormValue("state")
+	if stateFilter != "" && stateFilter != rules.StateFiring.String() && stateFilter != rules.StatePending.String() && stateFilter != rules.StateInactive.String() {
+		err := errors.Errorf("invalid state %q, must be one of %q, %q, %q", stateFilter, rules.StateInactive, rules.StatePending, rules.StateFiring)
+		return apiFuncResult{nil, &apiError{errorBadData, err}, nil, nil}
+	}
+
+	ruleNames := toSet(r.Form["rule_name[]"])
+	groupNames := toSet(r.Form["group_name[]"])
+	files := toSet(r.Form["file[]"])
+
+	matchers, err := parseMatchersParam(r.Form["match[]"])
+	if err != nil {
+		return apiFuncResult{nil, &apiError{errorBadData, err}, nil, nil}
+	}
+
+	limit, offset, err := parseLimitOffset(r)
+	if err != nil {
+		return apiFuncResult{nil, &apiError{errorBadData, err}, nil, nil}
+	}
+
+	ruleGroups := rr.RuleGroups()
+	res := &RuleDiscovery{RuleGroups: []*RuleGroup{}}
+	totalCount := 0
+	collected := 0
+	remainingOffset := offset
+
+	// Sort a copy by name so that, like alerts(), paginating across calls
+	// sees a stable order regardless of what order the retriever returns
+	// groups and rules in.
+	ruleGroups = append([]*rules.Group(nil), ruleGroups...)
+	sort.Slice(ruleGroups, func(i, j int) bool {
+		return ruleGroups[i].Name() < ruleGroups[j].Name()
+	})
+
+	for _, grp := range ruleGroups {
+		if len(groupNames) > 0 && !groupNames[grp.Name()] {
+			continue
+		}
+		if len(files) > 0 && !files[grp.File()] {
+			continue
+		}
+
 		apiRuleGroup := &RuleGroup{
 			Name:     grp.Name(),
 			File:     grp.File(),
@@ -783,7 +1192,19 @@ func (api *API) rules(r *http.Request) apiFuncResult {
 			Rules:    []rule{},
 		}
 
-		for _, r := range grp.Rules() {
+		groupRules := append([]rules.Rule(nil), grp.Rules()...)
+		sort.Slice(groupRules, func(i, j int) bool {
+			return groupRules[i].Name() < groupRules[j].Name()
+		})
+
+		for _, r := range groupRules {
+			if len(ruleNames) > 0 && !ruleNames[r.Name()] {
+				continue
+			}
+			if !matchLabels(r.Labels(), matchers) {
+				continue
+			}
+
 			var enrichedRule rule
 
 			lastError := ""
@@ -793,18 +1214,35 @@ func (api *API) rules(r *http.Request) apiFuncResult {
 
 			switch rule := r.(type) {
 			case *rules.AlertingRule:
+				if typeFilter == ruleTypeRecord {
+					continue
+				}
+				alerts := rulesAlertsToAPIAlerts(rule.ActiveAlerts())
+				if stateFilter != "" {
+					filtered := make([]*Alert, 0, len(alerts))
+					for _, a := range alerts {
+						if a.State == stateFilter {
+							filtered = append(filtered, a)
+						}
+					}
+					alerts = filtered
+				}
 				enrichedRule = alertingRule{
 					Name:        rule.Name(),
 					Query:       rule.Query().String(),
 					Duration:    rule.Duration().Seconds(),
 					Labels:      rule.Labels(),
 					Annotations: rule.Annotations(),
-					Alerts:      rulesAlertsToAPIAlerts(rule.ActiveAlerts()),
+					Alerts:      alerts,
 					Health:      rule.Health(),
 					LastError:   lastError,
+					QueryURL:    ruleQueryURL(queryURL, rule.Query().String()),
 					Type:        "alerting",
 				}
 			case *rules.RecordingRule:
+				if typeFilter == ruleTypeAlert {
+					continue
+				}
 				enrichedRule = recordingRule{
 					Name:      rule.Name(),
 					Query:     rule.Query().String(),
@@ -818,13 +1256,121 @@ func (api *API) rules(r *http.Request) apiFuncResult {
 				return apiFuncResult{nil, &apiError{errorInternal, err}, nil, nil}
 			}
 
+			totalCount++
+
+			// Pagination is applied across the flattened, filtered rule list
+			// while preserving group/rule ordering. collected tracks rules
+			// appended to res so far so enforcing limit doesn't require
+			// rescanning res on every rule.
+			if remainingOffset > 0 {
+				remainingOffset--
+				continue
+			}
+			if limit > 0 && collected >= limit {
+				continue
+			}
+
 			apiRuleGroup.Rules = append(apiRuleGroup.Rules, enrichedRule)
+			collected++
+		}
+
+		if len(apiRuleGroup.Rules) > 0 {
+			res.RuleGroups = append(res.RuleGroups, apiRuleGroup)
 		}
-		res.RuleGroups[i] = apiRuleGroup
 	}
+
+	res.TotalCount = totalCount
 	return apiFuncResult{res, nil, nil, nil}
 }
 
+// ruleQueryURL returns a deep link back into a query UI served at queryURL,
+// pre-populated with expr, or "" if queryURL is empty. It follows the same
+// g0.expr/g0.tab convention the Prometheus UI itself uses to restore a
+// graph from its URL.
+func ruleQueryURL(queryURL, expr string) string {
+	if queryURL == "" {
+		return ""
+	}
+	v := url.Values{}
+	v.Set("g0.expr", expr)
+	v.Set("g0.tab", "1")
+	return queryURL + "?" + v.Encode()
+}
+
+func toSet(values []string) map[string]bool {
+	if len(values) == 0 {
+		return nil
+	}
+	set := make(map[string]bool, len(values))
+	for _, v := range values {
+		set[v] = true
+	}
+	return set
+}
+
+// alertsStream upgrades to Server-Sent Events and streams rules.Event values
+// published whenever an alert changes state, scoped to EventAlertStateChange.
+func (api *API) alertsStream(w http.ResponseWriter, r *http.Request) {
+	api.streamRulesHub(w, r, rules.EventAlertStateChange)
+}
+
+// rulesStream upgrades to Server-Sent Events and streams rules.Event values
+// published whenever a rule finishes evaluating.
+func (api *API) rulesStream(w http.ResponseWriter, r *http.Request) {
+	api.streamRulesHub(w, r, rules.EventRuleEvaluated)
+}
+
+// streamRulesHub writes out Server-Sent Events for every rules.Event of the
+// given type published on api.rulesHub until the client disconnects.
+func (api *API) streamRulesHub(w http.ResponseWriter, r *http.Request, want rules.EventType) {
+	httputil.SetCORS(w, api.CORSOrigin, r)
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	if api.rulesHub == nil {
+		http.Error(w, "rule event streaming is not enabled", http.StatusNotImplemented)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	events, unsubscribe := api.rulesHub.Subscribe()
+	defer unsubscribe()
+
+	json := jsoniter.ConfigCompatibleWithStandardLibrary
+	ctx := r.Context()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case ev, ok := <-events:
+			if !ok {
+				return
+			}
+			if ev.Type != want {
+				continue
+			}
+			b, err := json.Marshal(ev)
+			if err != nil {
+				level.Error(api.logger).Log("msg", "error marshaling rule event", "err", err)
+				continue
+			}
+			if _, err := fmt.Fprintf(w, "data: %s\n\n", b); err != nil {
+				level.Error(api.logger).Log("msg", "error writing rule event", "err", err)
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
 type prometheusConfig struct {
 	YAML string `json:"yaml"`
 }
@@ -840,8 +1386,135 @@ func (api *API) serveFlags(r *http.Request) apiFuncResult {
 	return apiFuncResult{api.flagsMap, nil, nil, nil}
 }
 
+// tsdbStats returns the cardinality statistics for the current head block,
+// scoped to match[] if given, or an unavailable error if the configured
+// TSDBAdmin can't produce them.
+func (api *API) tsdbStats(r *http.Request) (*TSDBStatus, *apiError) {
+	matchers, err := parseMatchersParam(r.Form["match[]"])
+	if err != nil {
+		return nil, &apiError{errorBadData, err}
+	}
+
+	db := api.db()
+	if db == nil {
+		return nil, &apiError{errorUnavailable, errors.New("TSDB not ready")}
+	}
+	statsDB, ok := db.(TSDBStats)
+	if !ok {
+		return nil, &apiError{errorUnavailable, errors.New("TSDB does not support cardinality statistics")}
+	}
+
+	limit := 10
+	if s := r.FormValue("limit"); s != "" {
+		if limit, err = strconv.Atoi(s); err != nil || limit <= 0 {
+			return nil, &apiError{errorBadData, errors.New("limit must be a positive number")}
+		}
+	}
+
+	status, err := statsDB.Stats("", matchers...)
+	if err != nil {
+		return nil, &apiError{errorInternal, err}
+	}
+	return limitTSDBStats(status, limit), nil
+}
+
+// serveTSDBStatus answers GET /api/v1/status/tsdb with the full cardinality
+// breakdown: top-N series-per-metric, label names by distinct value count
+// and by memory use, label-value pairs by series count, and per-metric chunk
+// counts.
+func (api *API) serveTSDBStatus(r *http.Request) apiFuncResult {
+	if err := r.ParseForm(); err != nil {
+		return apiFuncResult{nil, &apiError{errorBadData, errors.Wrapf(err, "error parsing form values")}, nil, nil}
+	}
+	status, apiErr := api.tsdbStats(r)
+	if apiErr != nil {
+		return apiFuncResult{nil, apiErr, nil, nil}
+	}
+	return apiFuncResult{status, nil, nil, nil}
+}
+
+// serveTSDBLabelNames answers GET /api/v1/status/tsdb/labelnames with just
+// the top-N label names by distinct value count, for operators who only
+// need that one slice without paying to serialize the rest.
+func (api *API) serveTSDBLabelNames(r *http.Request) apiFuncResult {
+	if err := r.ParseForm(); err != nil {
+		return apiFuncResult{nil, &apiError{errorBadData, errors.Wrapf(err, "error parsing form values")}, nil, nil}
+	}
+	status, apiErr := api.tsdbStats(r)
+	if apiErr != nil {
+		return apiFuncResult{nil, apiErr, nil, nil}
+	}
+	return apiFuncResult{status.LabelValueCountByLabelName, nil, nil, nil}
+}
+
+// chunkedReadContentType is the Content-Type/Accept value that negotiates
+// this server's private chunked read mechanism in place of a single
+// buffered ReadResponse. Its frame and checksum layout resembles the
+// standard chunked remote-read wire format, but Chunk.Data itself is
+// encoded with remote.rawChunkEncoding, a non-standard, package-private
+// encoding (see its doc comment) rather than real TSDB chunks. The name is
+// deliberately unlike the standard protocol's media type so that a
+// Thanos/Cortex-style chunked remote-read client, which only recognizes
+// the standard type, never negotiates this path by accident.
+const chunkedReadContentType = "application/x-prometheus-internal-chunked-read"
+
+// isStreamingReadRequest reports whether r asked for this server's private
+// chunked read mechanism, either via the header used by existing internal
+// callers or by negotiating the content type directly. Neither name
+// overlaps the standard chunked remote-read negotiation surface, so a
+// generic remote-read client cannot trigger this path without deliberately
+// asking for it.
+func isStreamingReadRequest(r *http.Request) bool {
+	if r.Header.Get("X-Prometheus-Internal-Chunked-Read") == "true" {
+		return true
+	}
+	for _, accept := range r.Header["Accept"] {
+		if strings.Contains(accept, "x-prometheus-internal-chunked-read") {
+			return true
+		}
+	}
+	return false
+}
+
+// withReadDeadline derives a cancelable context from r.Context() that is
+// also canceled once api.remoteReadTimeout elapses, along with the
+// deadlineTimer driving that cancellation. Callers can call
+// timer.SetDeadline again to push the deadline out while the request is
+// still in flight; isDeadlineExceeded distinguishes a timeout from the
+// client simply disconnecting.
+func (api *API) withReadDeadline(r *http.Request) (context.Context, *deadlineTimer, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(r.Context())
+	timer := newDeadlineTimer()
+
+	if api.remoteReadTimeout > 0 {
+		timer.SetDeadline(time.Now().Add(api.remoteReadTimeout))
+		go func() {
+			select {
+			case <-timer.Done():
+				cancel()
+			case <-ctx.Done():
+			}
+		}()
+	}
+	return ctx, timer, cancel
+}
+
+// isDeadlineExceeded reports whether timer's deadline has already fired.
+func isDeadlineExceeded(timer *deadlineTimer) bool {
+	select {
+	case <-timer.Done():
+		return true
+	default:
+		return false
+	}
+}
+
 func (api *API) remoteRead(w http.ResponseWriter, r *http.Request) {
-	if err := api.remoteReadGate.Start(r.Context()); err != nil {
+	ctx, timer, cancel := api.withReadDeadline(r)
+	defer cancel()
+	defer timer.Stop()
+
+	if err := api.remoteReadGate.Start(ctx); err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -856,71 +1529,53 @@ func (api *API) remoteRead(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if isStreamingReadRequest(r) {
+		api.remoteReadStreamed(w, r, req, ctx, timer)
+		return
+	}
+
 	resp := prompb.ReadResponse{
 		Results: make([]*prompb.QueryResult, len(req.Queries)),
 	}
 	for i, query := range req.Queries {
-		from, through, matchers, selectParams, err := remote.FromQuery(query)
+		querier, filteredMatchers, selectParams, err := api.remoteReadQuerier(ctx, query)
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
-
-		querier, err := api.Queryable.Querier(r.Context(), from, through)
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
-		}
 		defer querier.Close()
 
-		// Change equality matchers which match external labels
-		// to a matcher that looks for an empty label,
-		// as that label should not be present in the storage.
-		externalLabels := api.config().GlobalConfig.ExternalLabels.Map()
-		filteredMatchers := make([]*labels.Matcher, 0, len(matchers))
-		for _, m := range matchers {
-			value := externalLabels[m.Name]
-			if m.Type == labels.MatchEqual && value == m.Value {
-				matcher, err := labels.NewMatcher(labels.MatchEqual, m.Name, "")
-				if err != nil {
-					http.Error(w, err.Error(), http.StatusInternalServerError)
-					return
-				}
-				filteredMatchers = append(filteredMatchers, matcher)
-			} else {
-				filteredMatchers = append(filteredMatchers, m)
-			}
-		}
-
-		set, _, err := querier.Select(selectParams, filteredMatchers...)
+		set, warnings, err := querier.Select(selectParams, filteredMatchers...)
 		if err != nil {
+			if isDeadlineExceeded(timer) {
+				http.Error(w, "remote read request timed out", http.StatusGatewayTimeout)
+				return
+			}
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
+		for _, warning := range warnings {
+			// prompb.ReadResponse has no field to carry partial-result
+			// warnings, unlike the JSON query endpoints, so the best we can
+			// do here is log them rather than drop them silently.
+			level.Warn(api.logger).Log("msg", "warnings on remote read query", "err", warning)
+		}
 		resp.Results[i], err = remote.ToQueryResult(set, api.remoteReadSampleLimit)
 		if err != nil {
 			if httpErr, ok := err.(remote.HTTPError); ok {
 				http.Error(w, httpErr.Error(), httpErr.Status())
 				return
 			}
+			if isDeadlineExceeded(timer) {
+				http.Error(w, "remote read request timed out", http.StatusGatewayTimeout)
+				return
+			}
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
 
-		// Add external labels back in, in sorted order.
-		sortedExternalLabels := make([]prompb.Label, 0, len(externalLabels))
-		for name, value := range externalLabels {
-			sortedExternalLabels = append(sortedExternalLabels, prompb.Label{
-				Name:  string(name),
-				Value: string(value),
-			})
-		}
-		sort.Slice(sortedExternalLabels, func(i, j int) bool {
-			return sortedExternalLabels[i].Name < sortedExternalLabels[j].Name
-		})
-
 		for _, ts := range resp.Results[i].Timeseries {
-			ts.Labels = mergeLabels(ts.Labels, sortedExternalLabels)
+			ts.Labels = mergeLabels(ts.Labels, api.sortedExternalLabels())
 		}
 	}
 
@@ -930,6 +1585,218 @@ func (api *API) remoteRead(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// remoteReadQuerier opens a querier for query's time range and returns the
+// matchers with any equality matcher on an external label rewritten to
+// match the empty label, since external labels are never present in local
+// storage.
+func (api *API) remoteReadQuerier(ctx context.Context, query *prompb.Query) (storage.Querier, []*labels.Matcher, *storage.SelectParams, error) {
+	from, through, matchers, selectParams, err := remote.FromQuery(query)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	querier, err := api.Queryable.Querier(ctx, from, through)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	externalLabels := api.config().GlobalConfig.ExternalLabels.Map()
+	filteredMatchers := make([]*labels.Matcher, 0, len(matchers))
+	for _, m := range matchers {
+		value := externalLabels[m.Name]
+		if m.Type == labels.MatchEqual && value == m.Value {
+			matcher, err := labels.NewMatcher(labels.MatchEqual, m.Name, "")
+			if err != nil {
+				querier.Close()
+				return nil, nil, nil, err
+			}
+			filteredMatchers = append(filteredMatchers, matcher)
+		} else {
+			filteredMatchers = append(filteredMatchers, m)
+		}
+	}
+	return querier, filteredMatchers, selectParams, nil
+}
+
+// sortedExternalLabels returns the configured external labels as sorted
+// prompb.Label pairs, ready to be merged back into a series' labels.
+func (api *API) sortedExternalLabels() []prompb.Label {
+	externalLabels := api.config().GlobalConfig.ExternalLabels.Map()
+	sortedExternalLabels := make([]prompb.Label, 0, len(externalLabels))
+	for name, value := range externalLabels {
+		sortedExternalLabels = append(sortedExternalLabels, prompb.Label{
+			Name:  string(name),
+			Value: string(value),
+		})
+	}
+	sort.Slice(sortedExternalLabels, func(i, j int) bool {
+		return sortedExternalLabels[i].Name < sortedExternalLabels[j].Name
+	})
+	return sortedExternalLabels
+}
+
+// chunkedFrameSeriesLimit bounds how many series are batched into a single
+// ChunkedReadResponse frame, so memory use stays proportional to one frame
+// rather than the whole result. api.remoteReadMaxBytesInFrame enforces a
+// complementary byte-size bound, since a handful of long series can blow
+// past a reasonable frame size well before chunkedFrameSeriesLimit is hit.
+// Both bounds only help a client decoding frames with remote.ChunkedReader;
+// see remoteReadStreamed's doc comment for why this isn't a standard
+// chunked remote-read stream a generic client can decode incrementally.
+const chunkedFrameSeriesLimit = 128
+
+// approxChunkedSeriesSize estimates the wire size of cs well enough to
+// decide when a frame should be flushed, without paying for a second full
+// proto.Marshal of every series just to measure it.
+func approxChunkedSeriesSize(cs *prompb.ChunkedSeries) int {
+	size := 0
+	for _, l := range cs.Labels {
+		size += len(l.Name) + len(l.Value)
+	}
+	for _, c := range cs.Chunks {
+		size += len(c.Data) + 24 // two int64 timestamps plus the type field
+	}
+	return size
+}
+
+// remoteReadStreamed answers req using this server's own chunked streaming
+// remote-read protocol: a sequence of length-prefixed ChunkedReadResponse
+// frames, each holding a bounded batch of series, flushed as they're
+// produced instead of being materialized into one prompb.ReadResponse. This
+// bounds server-side memory on large reads, but it is NOT interoperable
+// with Thanos/Cortex or any other standard chunked remote-read consumer:
+// Chunk.Data is this package's own remote.rawChunkEncoding, not a TSDB
+// chunk, so only a client built against remote.ChunkedReader can decode it
+// (see chunkedReadContentType and API.Register).
+func (api *API) remoteReadStreamed(w http.ResponseWriter, r *http.Request, req *prompb.ReadRequest, ctx context.Context, timer *deadlineTimer) {
+	w.Header().Set("Content-Type", chunkedReadContentType)
+	w.WriteHeader(http.StatusOK)
+
+	cw := remote.NewChunkedWriter(w)
+	externalLabels := api.sortedExternalLabels()
+
+	for i, query := range req.Queries {
+		querier, filteredMatchers, selectParams, err := api.remoteReadQuerier(ctx, query)
+		if err != nil {
+			if isDeadlineExceeded(timer) {
+				level.Error(api.logger).Log("msg", "remote read request timed out opening querier")
+				return
+			}
+			// The response status has already been written, so the error
+			// can only be surfaced as a trailing, series-less frame rather
+			// than a 4xx status.
+			if werr := cw.Write(&prompb.ChunkedReadResponse{QueryIndex: int64(i), Error: err.Error()}); werr != nil {
+				level.Error(api.logger).Log("msg", "error writing remote read error frame", "err", werr)
+			}
+			return
+		}
+		defer querier.Close()
+
+		set, _, err := querier.Select(selectParams, filteredMatchers...)
+		if err != nil {
+			if isDeadlineExceeded(timer) {
+				level.Error(api.logger).Log("msg", "remote read request timed out selecting series")
+				return
+			}
+			// The response status has already been written, so the error
+			// can only be surfaced as a trailing, series-less frame rather
+			// than a 4xx status.
+			if werr := cw.Write(&prompb.ChunkedReadResponse{QueryIndex: int64(i), Error: err.Error()}); werr != nil {
+				level.Error(api.logger).Log("msg", "error writing remote read error frame", "err", werr)
+			}
+			return
+		}
+
+		samplesInFrame := 0
+		bytesInFrame := 0
+		var batch []*prompb.ChunkedSeries
+		flush := func() error {
+			if len(batch) == 0 {
+				return nil
+			}
+			err := cw.Write(&prompb.ChunkedReadResponse{ChunkedSeries: batch, QueryIndex: int64(i)})
+			batch = nil
+			samplesInFrame = 0
+			bytesInFrame = 0
+			return err
+		}
+
+		for set.Next() {
+			if isDeadlineExceeded(timer) {
+				if err := flush(); err != nil {
+					level.Error(api.logger).Log("msg", "error writing chunked read frame", "err", err)
+				}
+				if werr := cw.Write(&prompb.ChunkedReadResponse{QueryIndex: int64(i), Error: "remote read request timed out"}); werr != nil {
+					level.Error(api.logger).Log("msg", "error writing remote read error frame", "err", werr)
+				}
+				return
+			}
+
+			series := set.At()
+			chunkedSeries, numSamples, err := remote.ToChunkedSeries(series, mergeLabels(labelsToPrompb(series.Labels()), externalLabels))
+			if err != nil {
+				// The response status has already been written, so the
+				// error can only be surfaced as a trailing, series-less
+				// frame rather than a 4xx status.
+				if werr := cw.Write(&prompb.ChunkedReadResponse{QueryIndex: int64(i), Error: err.Error()}); werr != nil {
+					level.Error(api.logger).Log("msg", "error writing remote read error frame", "err", werr)
+				}
+				return
+			}
+
+			samplesInFrame += numSamples
+			if api.remoteReadSampleLimit > 0 && samplesInFrame > api.remoteReadSampleLimit {
+				// The response status has already been written and frames
+				// may already have been flushed to the client, so the
+				// limit violation can only be surfaced as a trailing,
+				// series-less frame rather than a 4xx status.
+				if err := flush(); err != nil {
+					level.Error(api.logger).Log("msg", "error writing chunked read frame", "err", err)
+					return
+				}
+				err := errors.Errorf("exceeded remote_read_sample_limit of %d in one frame", api.remoteReadSampleLimit)
+				if werr := cw.Write(&prompb.ChunkedReadResponse{QueryIndex: int64(i), Error: err.Error()}); werr != nil {
+					level.Error(api.logger).Log("msg", "error writing remote read error frame", "err", werr)
+				}
+				return
+			}
+
+			batch = append(batch, chunkedSeries)
+			bytesInFrame += approxChunkedSeriesSize(chunkedSeries)
+			if len(batch) >= chunkedFrameSeriesLimit ||
+				(api.remoteReadMaxBytesInFrame > 0 && bytesInFrame >= api.remoteReadMaxBytesInFrame) {
+				if err := flush(); err != nil {
+					level.Error(api.logger).Log("msg", "error writing chunked read frame", "err", err)
+					return
+				}
+			}
+		}
+		if err := set.Err(); err != nil {
+			// The response status has already been written, so the error
+			// can only be surfaced as a trailing, series-less frame rather
+			// than a 4xx status.
+			if werr := cw.Write(&prompb.ChunkedReadResponse{QueryIndex: int64(i), Error: err.Error()}); werr != nil {
+				level.Error(api.logger).Log("msg", "error writing remote read error frame", "err", werr)
+			}
+			return
+		}
+		if err := flush(); err != nil {
+			level.Error(api.logger).Log("msg", "error writing chunked read frame", "err", err)
+			return
+		}
+	}
+}
+
+// labelsToPrompb converts labels.Labels to the equivalent sorted
+// []prompb.Label.
+func labelsToPrompb(lset labels.Labels) []prompb.Label {
+	result := make([]prompb.Label, 0, len(lset))
+	for _, l := range lset {
+		result = append(result, prompb.Label{Name: l.Name, Value: l.Value})
+	}
+	return result
+}
+
 func (api *API) deleteSeries(r *http.Request) apiFuncResult {
 	if !api.enableAdmin {
 		return apiFuncResult{nil, &apiError{errorUnavailable, errors.New("admin APIs disabled")}, nil, nil}
@@ -968,7 +1835,7 @@ func (api *API) deleteSeries(r *http.Request) apiFuncResult {
 		end = maxTime
 	}
 
-	for _, s := range r.Form["match[]"] {
+	for i, s := range r.Form["match[]"] {
 		matchers, err := promql.ParseMetricSelector(s)
 		if err != nil {
 			return apiFuncResult{nil, &apiError{errorBadData, err}, nil, nil}
@@ -980,7 +1847,11 @@ func (api *API) deleteSeries(r *http.Request) apiFuncResult {
 		}
 
 		if err := db.Delete(timestamp.FromTime(start), timestamp.FromTime(end), selector...); err != nil {
-			return apiFuncResult{nil, &apiError{errorInternal, err}, nil, nil}
+			var warnings storage.Warnings
+			if i > 0 {
+				warnings = append(warnings, errors.Errorf("only %d of %d match[] selectors were deleted before this error", i, len(r.Form["match[]"])))
+			}
+			return NewAPIError(errorInternal, err, warnings)
 		}
 	}
 
@@ -1021,9 +1892,40 @@ func (api *API) snapshot(r *http.Request) apiFuncResult {
 		return apiFuncResult{nil, &apiError{errorInternal, errors.Wrap(err, "create snapshot")}, nil, nil}
 	}
 
+	var warnings storage.Warnings
+	if skipHead {
+		warnings = append(warnings, errors.New("in-memory head block was excluded from the snapshot (skip_head=true)"))
+	}
+
+	snapURL := "file://" + dir
+	if api.snapshotSink != nil {
+		uploadedURL, err := snapshot.Upload(r.Context(), api.snapshotSink, name, dir)
+		if err != nil {
+			return NewAPIError(errorInternal, errors.Wrap(err, "upload snapshot"), warnings)
+		}
+		snapURL = uploadedURL
+
+		if api.snapshotBackend != "" && api.snapshotBackend != "local" {
+			// Only remove or prune local snapshot directories once this
+			// snapshot has actually been copied to a non-local sink: with the
+			// default "local" backend a local snapshot is the operator's only
+			// copy. A positive snapshotRetention keeps that many of the most
+			// recent local snapshot directories; 0 removes the local copy of
+			// this snapshot instead.
+			if api.snapshotRetention <= 0 {
+				if err := os.RemoveAll(dir); err != nil {
+					warnings = append(warnings, errors.Wrapf(err, "remove local copy of uploaded snapshot %q", name))
+				}
+			} else if err := snapshot.PruneLocal(snapdir, api.snapshotRetention); err != nil {
+				warnings = append(warnings, errors.Wrap(err, "prune old snapshots"))
+			}
+		}
+	}
+
 	return apiFuncResult{struct {
 		Name string `json:"name"`
-	}{name}, nil, nil, nil}
+		URL  string `json:"url"`
+	}{name, snapURL}, nil, warnings, nil}
 }
 
 func (api *API) cleanTombstones(r *http.Request) apiFuncResult {
@@ -1095,19 +1997,30 @@ func mergeLabels(primary, secondary []prompb.Label) []prompb.Label {
 }
 
 func (api *API) respond(w http.ResponseWriter, data interface{}, warnings storage.Warnings) {
-	statusMessage := statusSuccess
+	respondJSON(w, api.logger, data, warnings)
+}
+
+func (api *API) respondError(w http.ResponseWriter, apiErr *apiError, data interface{}, warnings storage.Warnings) {
+	respondJSONError(w, api.logger, apiErr, data, warnings)
+}
+
+// respondJSON writes a successful JSON envelope. It is a package-level
+// function rather than an (*API) method so that other types in this
+// package serving the same response shape, such as RulesAPI, don't need to
+// duplicate it.
+func respondJSON(w http.ResponseWriter, logger log.Logger, data interface{}, warnings storage.Warnings) {
 	var warningStrings []string
 	for _, warning := range warnings {
 		warningStrings = append(warningStrings, warning.Error())
 	}
 	json := jsoniter.ConfigCompatibleWithStandardLibrary
 	b, err := json.Marshal(&response{
-		Status:   statusMessage,
+		Status:   statusSuccess,
 		Data:     data,
 		Warnings: warningStrings,
 	})
 	if err != nil {
-		level.Error(api.logger).Log("msg", "error marshaling json response", "err", err)
+		level.Error(logger).Log("msg", "error marshaling json response", "err", err)
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -1115,20 +2028,27 @@ func (api *API) respond(w http.ResponseWriter, data interface{}, warnings storag
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	if n, err := w.Write(b); err != nil {
-		level.Error(api.logger).Log("msg", "error writing response", "bytesWritten", n, "err", err)
+		level.Error(logger).Log("msg", "error writing response", "bytesWritten", n, "err", err)
 	}
 }
 
-func (api *API) respondError(w http.ResponseWriter, apiErr *apiError, data interface{}) {
+// respondJSONError writes an error JSON envelope; see respondJSON for why
+// it is a package-level function.
+func respondJSONError(w http.ResponseWriter, logger log.Logger, apiErr *apiError, data interface{}, warnings storage.Warnings) {
+	var warningStrings []string
+	for _, warning := range warnings {
+		warningStrings = append(warningStrings, warning.Error())
+	}
 	json := jsoniter.ConfigCompatibleWithStandardLibrary
 	b, err := json.Marshal(&response{
 		Status:    statusError,
 		ErrorType: apiErr.typ,
 		Error:     apiErr.err.Error(),
 		Data:      data,
+		Warnings:  warningStrings,
 	})
 	if err != nil {
-		level.Error(api.logger).Log("msg", "error marshaling json response", "err", err)
+		level.Error(logger).Log("msg", "error marshaling json response", "err", err)
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -1152,7 +2072,7 @@ func (api *API) respondError(w http.ResponseWriter, apiErr *apiError, data inter
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(code)
 	if n, err := w.Write(b); err != nil {
-		level.Error(api.logger).Log("msg", "error writing response", "bytesWritten", n, "err", err)
+		level.Error(logger).Log("msg", "error writing response", "bytesWritten", n, "err", err)
 	}
 }
 
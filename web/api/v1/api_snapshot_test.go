@@ -0,0 +1,120 @@
+// Copyright 2019 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/prometheus/prometheus/storage/snapshot"
+	tsdbLabels "github.com/prometheus/prometheus/tsdb/labels"
+)
+
+type fakeTSDBAdmin struct {
+	dir string
+}
+
+func (f fakeTSDBAdmin) CleanTombstones() error { return nil }
+func (f fakeTSDBAdmin) Delete(mint, maxt int64, ms ...tsdbLabels.Matcher) error {
+	return nil
+}
+func (f fakeTSDBAdmin) Dir() string { return f.dir }
+func (f fakeTSDBAdmin) Snapshot(dir string, withHead bool) error {
+	return os.MkdirAll(dir, 0777)
+}
+
+type fakeSnapshotSink struct {
+	url string
+}
+
+func (s fakeSnapshotSink) Put(_ context.Context, _, _ string) (string, error) {
+	return s.url, nil
+}
+
+func listSnapshotDirs(t *testing.T, snapdir string) int {
+	t.Helper()
+	entries, err := ioutil.ReadDir(snapdir)
+	if os.IsNotExist(err) {
+		return 0
+	}
+	if err != nil {
+		t.Fatalf("ReadDir: %v", err)
+	}
+	return len(entries)
+}
+
+func newSnapshotTestAPI(t *testing.T, tsdbDir string, sink snapshot.Sink, backend string, retention int) *API {
+	t.Helper()
+	admin := fakeTSDBAdmin{dir: tsdbDir}
+	return &API{
+		enableAdmin:       true,
+		db:                func() TSDBAdmin { return admin },
+		snapshotSink:      sink,
+		snapshotBackend:   backend,
+		snapshotRetention: retention,
+	}
+}
+
+// takeSnapshots calls api.snapshot n times, each producing its own
+// timestamped snapshot directory under tsdbDir/snapshots.
+func takeSnapshots(t *testing.T, api *API, n int) {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		r := httptest.NewRequest("POST", "/api/v1/admin/tsdb/snapshot", nil)
+		res := api.snapshot(r)
+		if res.err != nil {
+			t.Fatalf("snapshot %d: %v", i, res.err)
+		}
+	}
+}
+
+func TestSnapshotDoesNotPruneLocalWithLocalBackend(t *testing.T) {
+	tsdbDir := t.TempDir()
+	api := newSnapshotTestAPI(t, tsdbDir, nil, "local", 1)
+
+	takeSnapshots(t, api, 3)
+
+	snapdir := filepath.Join(tsdbDir, "snapshots")
+	if got := listSnapshotDirs(t, snapdir); got != 3 {
+		t.Fatalf("got %d local snapshot dirs, want all 3 kept since no remote upload ever happened", got)
+	}
+}
+
+func TestSnapshotPrunesLocalAfterRemoteUpload(t *testing.T) {
+	tsdbDir := t.TempDir()
+	api := newSnapshotTestAPI(t, tsdbDir, fakeSnapshotSink{url: "s3://bucket/snap"}, "s3", 1)
+
+	takeSnapshots(t, api, 3)
+
+	snapdir := filepath.Join(tsdbDir, "snapshots")
+	if got := listSnapshotDirs(t, snapdir); got != 1 {
+		t.Fatalf("got %d local snapshot dirs, want 1 after pruning to retention=1 following remote upload", got)
+	}
+}
+
+func TestSnapshotDoesNotPruneWithNoSink(t *testing.T) {
+	tsdbDir := t.TempDir()
+	api := newSnapshotTestAPI(t, tsdbDir, nil, "", 1)
+
+	takeSnapshots(t, api, 3)
+
+	snapdir := filepath.Join(tsdbDir, "snapshots")
+	if got := listSnapshotDirs(t, snapdir); got != 3 {
+		t.Fatalf("got %d local snapshot dirs, want all 3 kept with no sink configured", got)
+	}
+}
@@ -0,0 +1,212 @@
+// Copyright 2019 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/prometheus/prometheus/pkg/labels"
+)
+
+func TestToSet(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		values []string
+		want   map[string]bool
+	}{
+		{"nil", nil, nil},
+		{"empty", []string{}, nil},
+		{"single", []string{"a"}, map[string]bool{"a": true}},
+		{"multiple", []string{"a", "b", "a"}, map[string]bool{"a": true, "b": true}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got := toSet(tc.values)
+			if len(got) != len(tc.want) {
+				t.Fatalf("toSet(%v) = %v, want %v", tc.values, got, tc.want)
+			}
+			for k := range tc.want {
+				if !got[k] {
+					t.Fatalf("toSet(%v) = %v, missing key %q", tc.values, got, k)
+				}
+			}
+		})
+	}
+}
+
+func mustParseMatcher(t *testing.T, name, value string) *labels.Matcher {
+	t.Helper()
+	m, err := labels.NewMatcher(labels.MatchEqual, name, value)
+	if err != nil {
+		t.Fatalf("labels.NewMatcher: %v", err)
+	}
+	return m
+}
+
+func TestMatchLabels(t *testing.T) {
+	lset := labels.FromStrings("job", "node", "severity", "critical")
+
+	for _, tc := range []struct {
+		name     string
+		matchers []*labels.Matcher
+		want     bool
+	}{
+		{"no matchers", nil, true},
+		{"single matching", []*labels.Matcher{mustParseMatcher(t, "job", "node")}, true},
+		{"single non-matching", []*labels.Matcher{mustParseMatcher(t, "job", "other")}, false},
+		{
+			"all matching combination",
+			[]*labels.Matcher{mustParseMatcher(t, "job", "node"), mustParseMatcher(t, "severity", "critical")},
+			true,
+		},
+		{
+			"one of several non-matching",
+			[]*labels.Matcher{mustParseMatcher(t, "job", "node"), mustParseMatcher(t, "severity", "warning")},
+			false,
+		},
+		{"missing label", []*labels.Matcher{mustParseMatcher(t, "absent", "x")}, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := matchLabels(lset, tc.matchers); got != tc.want {
+				t.Fatalf("matchLabels(%v, %v) = %v, want %v", lset, tc.matchers, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestParseMatchersParam(t *testing.T) {
+	t.Run("empty", func(t *testing.T) {
+		got, err := parseMatchersParam(nil)
+		if err != nil {
+			t.Fatalf("parseMatchersParam(nil): %v", err)
+		}
+		if len(got) != 0 {
+			t.Fatalf("parseMatchersParam(nil) = %v, want empty", got)
+		}
+	})
+
+	t.Run("single selector", func(t *testing.T) {
+		got, err := parseMatchersParam([]string{`{job="node"}`})
+		if err != nil {
+			t.Fatalf("parseMatchersParam: %v", err)
+		}
+		if len(got) != 1 || got[0].Name != "job" || got[0].Value != "node" {
+			t.Fatalf("got %v, want a single job=node matcher", got)
+		}
+	})
+
+	t.Run("multiple selectors flatten into one slice", func(t *testing.T) {
+		got, err := parseMatchersParam([]string{`{job="node"}`, `{severity="critical"}`})
+		if err != nil {
+			t.Fatalf("parseMatchersParam: %v", err)
+		}
+		if len(got) != 2 {
+			t.Fatalf("got %d matchers, want 2", len(got))
+		}
+	})
+
+	t.Run("invalid selector", func(t *testing.T) {
+		if _, err := parseMatchersParam([]string{`{job=`}); err == nil {
+			t.Fatal("expected an error for a malformed selector, got nil")
+		}
+	})
+}
+
+func TestParseLimitOffset(t *testing.T) {
+	newRequest := func(t *testing.T, query string) *http.Request {
+		t.Helper()
+		r, err := http.NewRequest(http.MethodGet, "http://example.com/?"+query, nil)
+		if err != nil {
+			t.Fatalf("http.NewRequest: %v", err)
+		}
+		if err := r.ParseForm(); err != nil {
+			t.Fatalf("ParseForm: %v", err)
+		}
+		return r
+	}
+
+	for _, tc := range []struct {
+		name       string
+		query      string
+		wantLimit  int
+		wantOffset int
+		wantErr    bool
+	}{
+		{"neither set", "", 0, 0, false},
+		{"limit only", "limit=10", 10, 0, false},
+		{"offset only", "offset=5", 0, 5, false},
+		{"both set", "limit=10&offset=5", 10, 5, false},
+		{"limit not a number", "limit=abc", 0, 0, true},
+		{"offset not a number", "offset=abc", 0, 0, true},
+		{"negative offset", "offset=-1", 0, 0, true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			limit, offset, err := parseLimitOffset(newRequest(t, tc.query))
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("parseLimitOffset(%q): expected an error, got none", tc.query)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseLimitOffset(%q): %v", tc.query, err)
+			}
+			if limit != tc.wantLimit || offset != tc.wantOffset {
+				t.Fatalf("parseLimitOffset(%q) = (%d, %d), want (%d, %d)", tc.query, limit, offset, tc.wantLimit, tc.wantOffset)
+			}
+		})
+	}
+}
+
+func TestPaginateAlerts(t *testing.T) {
+	mk := func(names ...string) []*Alert {
+		alerts := make([]*Alert, len(names))
+		for i, n := range names {
+			alerts[i] = &Alert{Labels: labels.FromStrings("alertname", n)}
+		}
+		return alerts
+	}
+	names := func(alerts []*Alert) []string {
+		out := make([]string, len(alerts))
+		for i, a := range alerts {
+			out[i] = a.Labels.Get("alertname")
+		}
+		return out
+	}
+
+	for _, tc := range []struct {
+		name          string
+		offset, limit int
+		want          []string
+	}{
+		{"no pagination", 0, 0, []string{"a", "b", "c", "d", "e"}},
+		{"limit only", 0, 2, []string{"a", "b"}},
+		{"offset only", 2, 0, []string{"c", "d", "e"}},
+		{"offset and limit", 1, 2, []string{"b", "c"}},
+		{"offset past end", 10, 2, []string{}},
+		{"limit past end", 3, 10, []string{"d", "e"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got := names(paginateAlerts(mk("a", "b", "c", "d", "e"), tc.offset, tc.limit))
+			if len(got) != len(tc.want) {
+				t.Fatalf("paginateAlerts(offset=%d, limit=%d) = %v, want %v", tc.offset, tc.limit, got, tc.want)
+			}
+			for i := range got {
+				if got[i] != tc.want[i] {
+					t.Fatalf("paginateAlerts(offset=%d, limit=%d) = %v, want %v", tc.offset, tc.limit, got, tc.want)
+				}
+			}
+		})
+	}
+}
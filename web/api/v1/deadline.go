@@ -0,0 +1,82 @@
+// Copyright 2019 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1
+
+import (
+	"sync"
+	"time"
+)
+
+// deadlineTimer is a single AfterFunc timer guarding a cancel channel, in
+// the spirit of the read/write deadlines net.Conn implementations keep:
+// SetDeadline can be called repeatedly to push the deadline out, and every
+// call atomically rearms the same underlying timer instead of leaking a new
+// one. Once the deadline passes (or SetDeadline is called with a zero time
+// after having been armed), Done's channel is closed exactly once.
+type deadlineTimer struct {
+	mtx     sync.Mutex
+	timer   *time.Timer
+	done    chan struct{}
+	expired bool
+}
+
+// newDeadlineTimer returns a deadlineTimer with no deadline set; Done never
+// fires until SetDeadline is called.
+func newDeadlineTimer() *deadlineTimer {
+	return &deadlineTimer{done: make(chan struct{})}
+}
+
+// SetDeadline arms (or rearms) the timer to fire at t. Calling it again
+// before expiry replaces the previous deadline.
+func (d *deadlineTimer) SetDeadline(t time.Time) {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+
+	if d.expired {
+		return
+	}
+
+	dur := time.Until(t)
+	if d.timer == nil {
+		d.timer = time.AfterFunc(dur, d.expire)
+		return
+	}
+	d.timer.Reset(dur)
+}
+
+func (d *deadlineTimer) expire() {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+	if d.expired {
+		return
+	}
+	d.expired = true
+	close(d.done)
+}
+
+// Done returns the channel that's closed once the deadline set by the most
+// recent SetDeadline call passes.
+func (d *deadlineTimer) Done() <-chan struct{} {
+	return d.done
+}
+
+// Stop prevents a future expiry from firing and releases the timer. It is a
+// no-op if the deadline has already passed.
+func (d *deadlineTimer) Stop() {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+	if d.timer != nil {
+		d.timer.Stop()
+	}
+}
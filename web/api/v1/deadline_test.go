@@ -0,0 +1,92 @@
+// Copyright 2019 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDeadlineTimerExpires(t *testing.T) {
+	d := newDeadlineTimer()
+	d.SetDeadline(time.Now().Add(10 * time.Millisecond))
+
+	select {
+	case <-d.Done():
+	case <-time.After(time.Second):
+		t.Fatal("deadline never fired")
+	}
+}
+
+func TestDeadlineTimerNotArmedNeverFires(t *testing.T) {
+	d := newDeadlineTimer()
+	select {
+	case <-d.Done():
+		t.Fatal("Done fired without SetDeadline ever being called")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+// TestDeadlineTimerResetMidFlight simulates a slow querier by rearming the
+// deadline before it fires: the original deadline must not fire, and Done
+// must instead close around the pushed-out deadline.
+func TestDeadlineTimerResetMidFlight(t *testing.T) {
+	d := newDeadlineTimer()
+	start := time.Now()
+	d.SetDeadline(start.Add(50 * time.Millisecond))
+
+	// Push the deadline out before it has a chance to fire.
+	time.Sleep(10 * time.Millisecond)
+	d.SetDeadline(start.Add(200 * time.Millisecond))
+
+	select {
+	case <-d.Done():
+		if elapsed := time.Since(start); elapsed < 150*time.Millisecond {
+			t.Fatalf("Done fired after %s, want at least 150ms (the rearmed deadline, not the original)", elapsed)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("deadline never fired after being rearmed")
+	}
+}
+
+func TestDeadlineTimerSetDeadlineAfterExpiryIsNoop(t *testing.T) {
+	d := newDeadlineTimer()
+	d.SetDeadline(time.Now().Add(5 * time.Millisecond))
+
+	select {
+	case <-d.Done():
+	case <-time.After(time.Second):
+		t.Fatal("deadline never fired")
+	}
+
+	done := d.Done()
+	d.SetDeadline(time.Now().Add(time.Hour))
+	select {
+	case <-done:
+	default:
+		t.Fatal("Done channel was reopened by SetDeadline after expiry")
+	}
+}
+
+func TestDeadlineTimerStopPreventsExpiry(t *testing.T) {
+	d := newDeadlineTimer()
+	d.SetDeadline(time.Now().Add(20 * time.Millisecond))
+	d.Stop()
+
+	select {
+	case <-d.Done():
+		t.Fatal("Done fired after Stop")
+	case <-time.After(100 * time.Millisecond):
+	}
+}
@@ -0,0 +1,103 @@
+// Copyright 2018 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1
+
+import (
+	"net/http"
+
+	"github.com/go-kit/kit/log"
+	"github.com/opentracing-contrib/go-stdlib/nethttp"
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/prometheus/common/route"
+
+	"github.com/prometheus/prometheus/util/httputil"
+)
+
+// RulesAPI is a minimal façade over the rules/alerts endpoints that can be
+// mounted independently of the full API type. It depends only on a
+// rulesRetriever and a query URL, so rulers and federators can embed just
+// the rules surface without pulling in TSDB or query-engine dependencies.
+type RulesAPI struct {
+	rulesRetriever rulesRetriever
+	queryURL       string
+	logger         log.Logger
+}
+
+// NewRulesAPI returns a RulesAPI serving rule state from rr. queryURL, if
+// non-empty, is used to populate alertingRule.QueryURL with a deep link
+// back into a query UI so consumers don't have to be told the Prometheus
+// base URL out of band; leave it empty to omit the field entirely.
+func NewRulesAPI(rr rulesRetriever, queryURL string, logger log.Logger) *RulesAPI {
+	if logger == nil {
+		logger = log.NewNopLogger()
+	}
+	return &RulesAPI{
+		rulesRetriever: rr,
+		queryURL:       queryURL,
+		logger:         logger,
+	}
+}
+
+// Register mounts /api/v1/rules and /api/v1/alerts on r. Every request is
+// gzip-compressed when the client accepts it and, if tracer is non-nil,
+// wrapped in an HTTP span named after the route. logger, if non-nil,
+// overrides the logger passed to NewRulesAPI for just this mount point, so
+// one RulesAPI can be mounted more than once with different logging (e.g.
+// a ruler vs. a federator); pass nil to keep using NewRulesAPI's logger.
+func (api *RulesAPI) Register(r *route.Router, tracer opentracing.Tracer, logger log.Logger) {
+	if logger == nil {
+		logger = api.logger
+	}
+	wrap := func(name string, f apiFunc) http.HandlerFunc {
+		hf := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			result := f(r)
+			if result.err != nil {
+				respondJSONError(w, logger, result.err, nil, result.warnings)
+				return
+			}
+			respondJSON(w, logger, result.data, result.warnings)
+		})
+
+		handler := httpCompression(hf)
+		if tracer != nil {
+			handler = nethttp.Middleware(tracer, handler, nethttp.OperationNameFunc(func(r *http.Request) string {
+				return name
+			}))
+		}
+		return handler.ServeHTTP
+	}
+
+	r.Get("/rules", wrap("rules", api.rules))
+	r.Get("/alerts", wrap("alerts", api.alerts))
+}
+
+// rules and alerts delegate to the same rulesImpl/alertsImpl that back
+// (*API).rules/(*API).alerts, so the type/rule_name[]/group_name[]/file[]/
+// state/match[]/limit/offset filtering and pagination chunk0-1 added to the
+// full API is never missing here — exactly the audience (rulers,
+// federators) that filtering was built for.
+func (api *RulesAPI) rules(r *http.Request) apiFuncResult {
+	return rulesImpl(api.rulesRetriever, api.queryURL, r)
+}
+
+func (api *RulesAPI) alerts(r *http.Request) apiFuncResult {
+	return alertsImpl(api.rulesRetriever, r)
+}
+
+// httpCompression gzip-encodes the response body when the client sent
+// Accept-Encoding: gzip, reusing the same CompressionHandler the full API
+// registers its own routes with.
+func httpCompression(h http.Handler) http.Handler {
+	return httputil.CompressionHandler{Handler: h}
+}
@@ -0,0 +1,191 @@
+// Copyright 2019 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/prometheus/prometheus/pkg/labels"
+	"github.com/prometheus/prometheus/rules"
+)
+
+// rulesPollingFallbackInterval is how often a rulesPollingFallback re-reads rule and
+// alert state off its rulesRetriever looking for changes to publish.
+const rulesPollingFallbackInterval = 15 * time.Second
+
+// alertStreamState is what poll remembers about an alert instance between
+// ticks, enough to publish a terminal EventAlertStateChange for it even
+// once it has dropped out of ActiveAlerts entirely.
+type alertStreamState struct {
+	state     string
+	groupName string
+	ruleName  string
+	labels    labels.Labels
+}
+
+// rulesPollingFallback republishes rule and alert state read from a
+// rulesRetriever onto a rules.Hub, so that /alerts/stream and /rules/stream
+// have something to serve even though this package has no direct hook into
+// rule evaluation: that lives in the rules.Manager, outside this package,
+// and nothing there calls Hub.Publish. Polling the same rulesRetriever the
+// /rules and /alerts endpoints already read from makes the streaming
+// endpoints work out of the box for every caller of NewAPI, rather than
+// requiring each of them to separately wire Publish calls into their own
+// evaluation loop.
+//
+// This is an approximation of push-based streaming, not the real thing: it
+// cannot see any state an alert passed through between two ticks, only
+// what rr reports at the moment of the tick, and every event is delayed by
+// up to rulesPollingFallbackInterval. A caller that needs the full
+// inactive->pending->firing->resolved transition sequence with no
+// collapsing or delay must feed its own rules.Hub from the evaluation loop
+// and pass it to NewAPI instead of relying on this poller.
+type rulesPollingFallback struct {
+	rr  rulesRetriever
+	hub *rules.Hub
+
+	mtx       sync.Mutex
+	lastState map[string]alertStreamState
+	lastEval  map[string]time.Time
+}
+
+func newRulesPollingFallback(rr rulesRetriever, hub *rules.Hub) *rulesPollingFallback {
+	return &rulesPollingFallback{
+		rr:        rr,
+		hub:       hub,
+		lastState: map[string]alertStreamState{},
+		lastEval:  map[string]time.Time{},
+	}
+}
+
+// run polls until ctx is canceled.
+func (p *rulesPollingFallback) run(ctx context.Context) {
+	ticker := time.NewTicker(rulesPollingFallbackInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			p.poll()
+		}
+	}
+}
+
+// poll publishes an EventRuleEvaluated for every rule whose
+// GetEvaluationTimestamp has advanced since the previous poll, and an
+// EventAlertStateChange for every alert whose state differs from what the
+// previous poll observed, including a terminal "resolved" transition for
+// alerts that have dropped out of ActiveAlerts since the last poll.
+func (p *rulesPollingFallback) poll() {
+	seen := map[string]bool{}
+
+	for _, grp := range p.rr.RuleGroups() {
+		for _, r := range grp.Rules() {
+			p.publishIfEvaluated(grp.Name(), r)
+
+			ar, ok := r.(*rules.AlertingRule)
+			if !ok {
+				continue
+			}
+			for _, a := range ar.ActiveAlerts() {
+				key := fmt.Sprintf("%s/%s/%v", grp.Name(), ar.Name(), a.Labels)
+				seen[key] = true
+				p.publishStateChange(key, grp.Name(), ar.Name(), a)
+			}
+		}
+	}
+
+	p.mtx.Lock()
+	var resolved []alertStreamState
+	for key, st := range p.lastState {
+		if !seen[key] {
+			resolved = append(resolved, st)
+			delete(p.lastState, key)
+		}
+	}
+	p.mtx.Unlock()
+
+	for _, st := range resolved {
+		p.hub.Publish(&rules.Event{
+			Type:          rules.EventAlertStateChange,
+			GroupName:     st.groupName,
+			RuleName:      st.ruleName,
+			Labels:        st.labels,
+			PreviousState: st.state,
+			NextState:     "resolved",
+		})
+	}
+}
+
+// publishIfEvaluated publishes EventRuleEvaluated for r unless its
+// GetEvaluationTimestamp is unchanged since the last poll, so a 15s tick
+// where a rule's evaluation interval hasn't elapsed doesn't republish a
+// stale evaluation as if it were new.
+func (p *rulesPollingFallback) publishIfEvaluated(groupName string, r rules.Rule) {
+	evalKey := groupName + "/" + r.Name()
+	evalTS := r.GetEvaluationTimestamp()
+
+	p.mtx.Lock()
+	last, known := p.lastEval[evalKey]
+	p.lastEval[evalKey] = evalTS
+	p.mtx.Unlock()
+
+	if known && !evalTS.After(last) {
+		return
+	}
+
+	lastError := ""
+	if r.LastError() != nil {
+		lastError = r.LastError().Error()
+	}
+	p.hub.Publish(&rules.Event{
+		Type:      rules.EventRuleEvaluated,
+		GroupName: groupName,
+		RuleName:  r.Name(),
+		LastError: lastError,
+	})
+}
+
+func (p *rulesPollingFallback) publishStateChange(key, groupName, ruleName string, a *rules.Alert) {
+	state := a.State.String()
+
+	p.mtx.Lock()
+	prev, known := p.lastState[key]
+	if known && prev.state == state {
+		p.mtx.Unlock()
+		return
+	}
+	p.lastState[key] = alertStreamState{
+		state:     state,
+		groupName: groupName,
+		ruleName:  ruleName,
+		labels:    a.Labels,
+	}
+	p.mtx.Unlock()
+
+	activeAt := a.ActiveAt
+	p.hub.Publish(&rules.Event{
+		Type:          rules.EventAlertStateChange,
+		GroupName:     groupName,
+		RuleName:      ruleName,
+		Labels:        a.Labels,
+		PreviousState: prev.state,
+		NextState:     state,
+		ActiveAt:      &activeAt,
+	})
+}